@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"simulator/pkg/synthetic"
+	"time"
+)
+
+func genCarbonCommand(args []string) error {
+	fs := flag.NewFlagSet("gen-carbon", flag.ContinueOnError)
+	model := synthetic.Default()
+	var start, timeZone, outputPath string
+	var seed int64
+	fs.StringVar(&start, "start", model.Start.Format(time.DateOnly), "local date the trace starts on, e.g. 2023-01-01")
+	fs.DurationVar(&model.Duration, "duration", model.Duration, "length of the trace")
+	fs.DurationVar(&model.Interval, "interval", model.Interval, "sampling interval of the trace")
+	fs.StringVar(&timeZone, "time-zone", "UTC", "IANA time zone the trace's hours and weekdays are read in")
+	fs.Float64Var(&model.Base, "base", model.Base, "mean carbon intensity in kgCO2/MWh")
+	fs.Float64Var(&model.Diurnal, "diurnal", model.Diurnal, "relative amplitude of the daily cycle")
+	fs.Float64Var(&model.DiurnalPeak, "diurnal-peak", model.DiurnalPeak, "local hour the daily cycle peaks at")
+	fs.Float64Var(&model.Weekly, "weekly", model.Weekly, "relative drop in intensity on weekends")
+	fs.Float64Var(&model.Solar, "solar", model.Solar, "relative depth of the midday solar trough")
+	fs.Float64Var(&model.Sunrise, "sunrise", model.Sunrise, "local hour solar generation starts")
+	fs.Float64Var(&model.Sunset, "sunset", model.Sunset, "local hour solar generation ends")
+	fs.Float64Var(&model.Ramp, "ramp", model.Ramp, "relative height of the evening ramp after sunset")
+	fs.Float64Var(&model.Noise, "noise", model.Noise, "relative standard deviation of the noise")
+	fs.Float64Var(&model.Correlation, "noise-correlation", model.Correlation, "correlation between the noise of consecutive intervals, 0-1")
+	fs.Float64Var(&model.RegimeRate, "regime-rate", model.RegimeRate, "expected regime shifts per day")
+	fs.Float64Var(&model.RegimeShift, "regime-shift", model.RegimeShift, "relative standard deviation of the intensity level after a regime shift")
+	fs.Int64Var(&seed, "seed", 0, "seed for the trace's random draws, 0 picks one from the clock")
+	fs.StringVar(&outputPath, "output", "-", "file to write the carbon data CSV to, - for stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return usageErrorf("invalid time zone %q: %v", timeZone, err)
+	}
+	model.Location = location
+	if model.Start, err = time.ParseInLocation(time.DateOnly, start, location); err != nil {
+		return usageErrorf("invalid start date %q, please use YYYY-MM-DD", start)
+	}
+	if err := model.Validate(); err != nil {
+		return usageErrorf("%v", err)
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	log.Println("Using seed", seed)
+	data, err := synthetic.Generate(model, seed)
+	if err != nil {
+		return err
+	}
+	var out io.Writer = os.Stdout
+	if outputPath != "-" {
+		outFile, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %w", err)
+		}
+		defer outFile.Close()
+		out = outFile
+	}
+	return synthetic.WriteCSV(out, data)
+}
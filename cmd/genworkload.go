@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"simulator/pkg/config"
+	"simulator/pkg/environment"
+	"simulator/pkg/workload"
+	"time"
+)
+
+func registerGenWorkloadFlags(fs *flag.FlagSet, exp *config.Experiment) {
+	registerDataFlags(fs, exp)
+	registerWorkloadFlags(fs, exp)
+}
+
+func genWorkloadCommand(args []string) error {
+	fs := flag.NewFlagSet("gen-workload", flag.ContinueOnError)
+	opts := newExperimentOptions(fs, registerGenWorkloadFlags)
+	var outputPath string
+	fs.StringVar(&outputPath, "output", "-", "file to write the workload to, - for stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	experiments, err := opts.experiments()
+	if err != nil {
+		return err
+	}
+	if len(experiments) != 1 {
+		return usageErrorf("gen-workload needs a single experiment, the config describes %d", len(experiments))
+	}
+	exp := experiments[0]
+	jobInfo, err := exp.JobInfo()
+	if err != nil {
+		return err
+	}
+	dataLoader, err := exp.LoadData()
+	if err != nil {
+		return err
+	}
+	modelDirectory, err := exp.LoadModels()
+	if err != nil {
+		return err
+	}
+	if exp.Seed == 0 {
+		exp.Seed = time.Now().UnixNano()
+	}
+	env, err := environment.NewEnvironment(dataLoader, modelDirectory, exp.Seed)
+	if err != nil {
+		return err
+	}
+	log.Println("Using seed", env.Seed)
+	generated, err := workload.GetWorkload(env, jobInfo)
+	if err != nil {
+		return err
+	}
+	var out io.Writer = os.Stdout
+	if outputPath != "-" {
+		outFile, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %w", err)
+		}
+		defer outFile.Close()
+		out = outFile
+	}
+	return workload.WriteJobsCSV(out, generated.Jobs)
+}
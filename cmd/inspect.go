@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"simulator/pkg/config"
+)
+
+func inspectDataCommand(args []string) error {
+	fs := flag.NewFlagSet("inspect-data", flag.ContinueOnError)
+	exp := config.Default()
+	var printAll, printGaps bool
+	registerDataFlags(fs, &exp)
+	fs.BoolVar(&printAll, "all", false, "print every data point")
+	fs.BoolVar(&printGaps, "gaps", false, "list every gap in the data")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if exp.Region == "" && exp.DataFile == "" {
+		return usageErrorf("either -region or -data is required")
+	}
+	dataLoader, err := exp.LoadData()
+	if err != nil {
+		return err
+	}
+	if printAll {
+		return dataLoader.PrintAllData()
+	}
+	minIntensity, maxIntensity, totalIntensity := math.MaxFloat64, -math.MaxFloat64, 0.0
+	for _, dataPoint := range dataLoader.Data {
+		minIntensity = min(minIntensity, dataPoint.CarbonIntensity)
+		maxIntensity = max(maxIntensity, dataPoint.CarbonIntensity)
+		totalIntensity += dataPoint.CarbonIntensity
+	}
+	fmt.Println(dataLoader)
+	fmt.Printf("\tEntries: %d\n", dataLoader.NumEntries())
+	fmt.Printf("\tTime Zone: %v\n", dataLoader.Location())
+	fmt.Printf("\tStart Date: %v\n", dataLoader.StartDate().In(dataLoader.Location()))
+	fmt.Printf("\tEnd Date: %v\n", dataLoader.EndDate().In(dataLoader.Location()))
+	fmt.Printf("\tSampling Interval: %v\n", dataLoader.Interval())
+	quality := dataLoader.Quality()
+	fmt.Printf("\tRows Read: %d\n", quality.Rows)
+	fmt.Printf("\tData Quality: %s\n", quality)
+	if printGaps {
+		for _, gap := range quality.Gaps {
+			fmt.Printf("\t\tGap: %s\n", gap)
+		}
+	}
+	fmt.Printf("\tCarbon Intensity (kgCO2/MWh): min %f, mean %f, max %f\n", minIntensity, totalIntensity/float64(dataLoader.NumEntries()), maxIntensity)
+	if dataLoader.HasMarginal() {
+		minMarginal, maxMarginal, totalMarginal := math.MaxFloat64, -math.MaxFloat64, 0.0
+		for _, dataPoint := range dataLoader.Data {
+			minMarginal = min(minMarginal, dataPoint.MarginalIntensity)
+			maxMarginal = max(maxMarginal, dataPoint.MarginalIntensity)
+			totalMarginal += dataPoint.MarginalIntensity
+		}
+		fmt.Printf("\tMarginal Emission Rate (kgCO2/MWh): min %f, mean %f, max %f\n", minMarginal, totalMarginal/float64(dataLoader.NumEntries()), maxMarginal)
+	}
+	return nil
+}
@@ -1,140 +1,80 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
-	"simulator/pkg/directory"
-	"simulator/pkg/loader"
-	"simulator/pkg/simulator"
-	"simulator/pkg/simulator/policies"
-	"simulator/pkg/workload"
-	"strconv"
-	"time"
+	"simulator/pkg/config"
 )
 
-func chooseRegion() string {
-	switch os.Args[1] {
-	case "CAISO":
-		return "CAISO.csv"
-	case "ERCOT":
-		return "ERCOT.csv"
-	case "MISO":
-		return "MISO.csv"
-	case "NYISO":
-		return "NYISO.csv"
-	default:
-		panic("Invalid region specified. Please choose CAISO, ERCOT, MISO, or NYISO.")
-	}
+// usageError marks errors caused by invalid command line input, which exit
+// with status 2 rather than 1.
+type usageError struct {
+	msg string
 }
 
-func chooseSLO() time.Duration {
-	switch os.Args[2] {
-	case "30min":
-		return 30 * time.Minute
-	case "1hr":
-		return 1 * time.Hour
-	case "6hr":
-		return 6 * time.Hour
-	case "12hr":
-		return 12 * time.Hour
-	case "24hr":
-		return 24 * time.Hour
-	default:
-		panic("Invalid SLO specified. Please choose 1hr, 6hr, 12hr, or 24hr.")
-	}
+func (u *usageError) Error() string {
+	return u.msg
 }
 
-func chooseWorkload() string {
-	switch os.Args[3] {
-	case "random":
-		return "random"
-	case "uniform":
-		return "uniform"
-	case "morningSpike":
-		return "morningSpike"
-	case "afternoonSpike":
-		return "afternoonSpike"
-	case "eveningSpike":
-		return "eveningSpike"
-	case "nightSpike":
-		return "nightSpike"
-	case "weekdaySpike":
-		return "weekdaySpike"
-	case "weekendSpike":
-		return "weekendSpike"
-	default:
-		panic("Invalid workload specified. Please choose random, uniform, or normal.")
-	}
+func usageErrorf(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
 }
 
-func choosePolicy() simulator.PolicyInterface {
-	switch os.Args[4] {
-	case "fifo":
-		model, err := directory.FetchDirectory().GetModelDefinition(os.Args[5])
-		if err != nil {
-			panic("Error getting model definition")
-		}
-		return policies.NewFIFO(model)
-	case "temporal":
-		model, err := directory.FetchDirectory().GetModelDefinition(os.Args[5])
-		if err != nil {
-			panic("Error getting model definition")
-		}
-		return policies.NewTemporal(model, 0)
-	case "modelSelection":
-		accuracy, err := strconv.ParseFloat(os.Args[5], 64)
-		if err != nil {
-			panic("Error parsing accuracy")
-		}
-		return policies.NewModelSelection(accuracy)
-	case "hybridSelection":
-		accuracy, err := strconv.ParseFloat(os.Args[5], 64)
-		if err != nil {
-			panic("Error parsing accuracy")
-		}
-		return policies.NewHybridSelection(accuracy, 0)
-	default:
-		panic("Invalid policy specified. Please choose fifo, temporal, modelSelection, or hybridSelection.")
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{"run", "run a single simulation", runCommand},
+	{"sweep", "run replications of a configuration and report confidence intervals", sweepCommand},
+	{"validate", "check a configuration and its input files without simulating", validateCommand},
+	{"inspect-data", "summarise a carbon intensity data file", inspectDataCommand},
+	{"gen-workload", "generate a workload and write its arrivals to CSV", genWorkloadCommand},
+	{"gen-carbon", "generate a synthetic carbon intensity trace and write it to CSV", genCarbonCommand},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.description)
 	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command.\n", os.Args[0])
 }
 
 func main() {
-	currDir, err := os.Getwd()
-	if err != nil {
-		fmt.Println("Error getting current directory:", err)
-		return
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
 	}
-	/*
-		Load in carbon emission data
-	*/
-	dataPath := filepath.Join(currDir, "..", "data", "collected", chooseRegion())
-	dataLoader := loader.NewLoader(dataPath)
-	log.Println(dataLoader)
-
-	/*
-		Load in AI Model Definitions & Workload information
-	*/
-	modelPath := filepath.Join(currDir, "..", "cmd", "AIModels.json")
-	_ = directory.NewDirectory(modelPath)
-
-	/*
-		Generate and load in workload information
-	*/
-	jobInfo := workload.NewJobInfo(chooseSLO(), 1000000, chooseWorkload())
-	workload := workload.GetWorkload(jobInfo)
-
-	/*
-		Initialize the simulator
-	*/
-	simElement := simulator.NewSimulator(workload.Jobs, choosePolicy())
-	if simElement == nil {
-		log.Println("Simulator not initialized. Exiting.")
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		usage()
 		return
 	}
-	log.Println(simElement)
-	simElement.Begin()
-	log.Println("Simulation complete.")
-	log.Println(simElement)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(os.Args[2:])
+		var usageErr *usageError
+		var validationErr *config.ValidationError
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return
+		case errors.As(err, &usageErr), errors.As(err, &validationErr):
+			fmt.Fprintf(os.Stderr, "%s: %v\nRun '%s %s -h' for usage.\n", name, err, os.Args[0], name)
+			os.Exit(2)
+		default:
+			log.Printf("%s: %v", name, err)
+			os.Exit(1)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q.\n\n", name)
+	usage()
+	os.Exit(2)
 }
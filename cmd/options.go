@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"simulator/pkg/config"
+	"simulator/pkg/simulator"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// slotsFlag parses per-model slot requirements such as small=1,large=4.
+type slotsFlag struct {
+	slots *map[string]int
+}
+
+func (s slotsFlag) String() string {
+	if s.slots == nil || len(*s.slots) == 0 {
+		return ""
+	}
+	entries := make([]string, 0, len(*s.slots))
+	for _, name := range slices.Sorted(maps.Keys(*s.slots)) {
+		entries = append(entries, name+"="+strconv.Itoa((*s.slots)[name]))
+	}
+	return strings.Join(entries, ",")
+}
+
+func (s slotsFlag) Set(value string) error {
+	slotMap := make(map[string]int)
+	for _, entry := range strings.Split(value, ",") {
+		name, count, found := strings.Cut(entry, "=")
+		if !found {
+			return fmt.Errorf("invalid slot requirement %q, please use model=slots", entry)
+		}
+		numSlots, err := strconv.Atoi(count)
+		if err != nil || numSlots <= 0 {
+			return fmt.Errorf("invalid slot count for model %s", name)
+		}
+		slotMap[name] = numSlots
+	}
+	*s.slots = slotMap
+	return nil
+}
+
+// floatListFlag parses comma separated numbers such as 0.5,1,2.
+type floatListFlag struct {
+	values *[]float64
+}
+
+func (f floatListFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	entries := make([]string, len(*f.values))
+	for i, value := range *f.values {
+		entries[i] = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return strings.Join(entries, ",")
+}
+
+func (f floatListFlag) Set(value string) error {
+	entries := strings.Split(value, ",")
+	values := make([]float64, len(entries))
+	for i, entry := range entries {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(entry), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", entry)
+		}
+		values[i] = parsed
+	}
+	*f.values = values
+	return nil
+}
+
+func registerDataFlags(fs *flag.FlagSet, exp *config.Experiment) {
+	fs.StringVar(&exp.Region, "region", exp.Region, "region to load carbon data for: CAISO, ERCOT, MISO or NYISO")
+	fs.StringVar(&exp.DataDir, "data-dir", exp.DataDir, "directory holding <region>.csv carbon data files")
+	fs.StringVar(&exp.DataFile, "data", exp.DataFile, "carbon data file to load, overrides -region and -data-dir")
+	fs.StringVar(&exp.ModelsFile, "models", exp.ModelsFile, "AI model definitions file")
+	fs.StringVar(&exp.TimeZone, "time-zone", exp.TimeZone, "IANA time zone of the carbon data's region, defaults to the region's own or UTC for -data")
+	fs.StringVar(&exp.DataSchema.Provider, "data-provider", exp.DataSchema.Provider, "provider whose export layout the carbon data follows: singularity, electricitymaps, watttime or ukgrid (default singularity)")
+	fs.StringVar(&exp.DataSchema.Format, "data-format", exp.DataSchema.Format, "carbon data format: csv or json, inferred from the extension by default")
+	fs.StringVar(&exp.DataSchema.Records, "data-records", exp.DataSchema.Records, "dotted path to the array of records in JSON carbon data, overriding the provider's")
+	fs.StringVar(&exp.DataSchema.Time, "data-time-column", exp.DataSchema.Time, "column or dotted JSON field holding each interval's start, overriding the provider's")
+	fs.StringVar(&exp.DataSchema.TimeLayout, "data-time-layout", exp.DataSchema.TimeLayout, "Go layout of the carbon data's times, RFC 3339 or Unix seconds by default")
+	fs.StringVar(&exp.DataSchema.Intensity, "data-intensity-column", exp.DataSchema.Intensity, "column or dotted JSON field holding the carbon intensity, overriding the provider's")
+	fs.StringVar(&exp.DataSchema.Marginal, "data-marginal-column", exp.DataSchema.Marginal, "column or dotted JSON field holding the marginal emission rate, overriding the provider's")
+	fs.StringVar(&exp.DataSchema.Unit, "data-unit", exp.DataSchema.Unit, "unit of the carbon intensity: kg/MWh, g/kWh or lbs/MWh, overriding the provider's")
+	fs.StringVar(&exp.DataQuality.Resample, "resample", exp.DataQuality.Resample, "interval to resample the carbon data to, e.g. 5m, empty to keep the data's own")
+	fs.StringVar(&exp.DataQuality.Interpolation, "interpolation", exp.DataQuality.Interpolation, "how -resample fills times between entries: previous, linear or cubic (default previous)")
+	fs.StringVar(&exp.Signal.Decision, "decision-signal", exp.Signal.Decision, "emission factor policies optimise against: average or marginal (default average)")
+	fs.StringVar(&exp.Signal.Accounting, "accounting-signal", exp.Signal.Accounting, "emission factor total carbon is reported in: average or marginal (default average)")
+	fs.BoolVar(&exp.DataQuality.Strict, "strict", exp.DataQuality.Strict, "refuse carbon data with gaps longer than -max-gap")
+	fs.StringVar(&exp.DataQuality.MaxGap, "max-gap", exp.DataQuality.MaxGap, "longest gap in the carbon data -strict accepts, e.g. 1h, by default no gap at all")
+}
+
+func registerWorkloadFlags(fs *flag.FlagSet, exp *config.Experiment) {
+	fs.StringVar(&exp.SLO, "slo", exp.SLO, "time each job has to complete: a duration such as 6hr or 90m, a range such as 30m-2h, or classes such as interactive:0.3:30m,batch:0.7:24h")
+	fs.StringVar(&exp.Workload.Name, "workload", exp.Workload.Name, "workload shape: random, uniform, spike, morningSpike, afternoonSpike, eveningSpike, nightSpike, weekdaySpike, weekendSpike, trace, poisson, nhpp, mmpp or mixture")
+	fs.IntVar(&exp.Workload.Jobs, "jobs", exp.Workload.Jobs, "number of jobs to generate, or the most arrivals the trace workload replays")
+	fs.Float64Var(&exp.Workload.SpikeFraction, "spike-fraction", exp.Workload.SpikeFraction, "share of jobs spike workloads place in the spike period")
+	fs.StringVar(&exp.Workload.SpikeWindows, "spike-windows", exp.Workload.SpikeWindows, "spike hours such as 5-12,17-20, overriding those of named spikes")
+	fs.StringVar(&exp.Workload.SpikeDays, "spike-days", exp.Workload.SpikeDays, "days the spike covers: all, weekday or weekend, overriding those of named spikes")
+	fs.StringVar(&exp.Workload.TimeZone, "workload-time-zone", exp.Workload.TimeZone, "IANA time zone spike hours and rate profiles are interpreted in, defaults to the data's")
+	fs.Float64Var(&exp.Workload.Rate, "rate", exp.Workload.Rate, "arrivals per hour for the poisson and nhpp workloads")
+	fs.Var(floatListFlag{&exp.Workload.RateProfile}, "rate-profile", "24 hourly or 168 weekly (from Monday) comma separated rate multipliers for the nhpp workload")
+	fs.StringVar(&exp.Workload.MMPP, "mmpp", exp.Workload.MMPP, "rate:dwell states for the mmpp workload, e.g. 20:2h,200:15m")
+	fs.StringVar(&exp.Workload.Mixture, "mix", exp.Workload.Mixture, "weighted components of the mixture workload, e.g. weekdaySpike:0.6,nightSpike:0.4")
+	fs.StringVar(&exp.Workload.Arrivals, "arrivals", exp.Workload.Arrivals, "arrival log in CSV or JSON Lines replayed by the trace workload")
+	fs.StringVar(&exp.Workload.ArrivalsFormat, "arrivals-format", exp.Workload.ArrivalsFormat, "arrival log format: csv or jsonl, inferred from the extension by default")
+	fs.StringVar(&exp.Workload.Align, "align", exp.Workload.Align, "shift logged arrivals onto the carbon data: none, start or week")
+	fs.Int64Var(&exp.Seed, "seed", exp.Seed, "seed for workload generation and runtime sampling, 0 picks one from the clock")
+}
+
+func registerExperimentFlags(fs *flag.FlagSet, exp *config.Experiment) {
+	registerDataFlags(fs, exp)
+	registerWorkloadFlags(fs, exp)
+	fs.StringVar(&exp.Policy.Name, "policy", exp.Policy.Name, "scheduling policy: fifo, temporal, interruptible, spatial, spatioTemporal, modelSelection, hybridSelection or oracle")
+	fs.StringVar(&exp.Policy.Model, "model", exp.Policy.Model, "model used by the fifo, temporal, interruptible and spatial policies, or the only model the oracle may use")
+	fs.Float64Var(&exp.Policy.Accuracy, "accuracy", exp.Policy.Accuracy, "required mean accuracy for the modelSelection, hybridSelection and oracle policies")
+	fs.StringVar(&exp.Policy.AccuracyConstraint, "accuracy-constraint", exp.Policy.AccuracyConstraint, "how the selection policies enforce -accuracy: global, window:N for the last N jobs, window:T for the last T such as 6h, tenant or job")
+	fs.Float64Var(&exp.Policy.SafeguardSD, "safeguard-sd", exp.Policy.SafeguardSD, "standard deviations of runtime the temporal policies leave before the deadline")
+	fs.StringVar(&exp.Policy.CheckpointOverhead, "checkpoint-overhead", exp.Policy.CheckpointOverhead, "time the interruptible policy's jobs spend restoring a checkpoint on every resume, e.g. 2m")
+	fs.IntVar(&exp.Cluster.Capacity, "capacity", exp.Cluster.Capacity, "number of accelerators in the cluster, 0 for unlimited")
+	fs.Var(slotsFlag{&exp.Cluster.Slots}, "slots", "per-model slot requirements, e.g. small=1,medium=2,large=4")
+	fs.Float64Var(&exp.Forecast.Error, "forecast-error", exp.Forecast.Error, "relative standard deviation of the carbon forecast error policies see, 0 for perfect forecasts")
+	fs.Float64Var(&exp.Forecast.Growth, "forecast-growth", exp.Forecast.Growth, "added relative forecast error per hour of lead time")
+	fs.Float64Var(&exp.Forecast.Correlation, "forecast-correlation", exp.Forecast.Correlation, "correlation between forecast errors of consecutive intervals, 0-1")
+	fs.StringVar(&exp.Forecast.Lag, "forecast-lag", exp.Forecast.Lag, "duration the forecast trails the carbon data by, e.g. 24h")
+	fs.StringVar(&exp.Forecast.Horizon, "forecast-horizon", exp.Forecast.Horizon, "how far ahead policies can see carbon forecasts, e.g. 48h, empty for unlimited")
+	fs.StringVar(&exp.Forecast.File, "forecast-file", exp.Forecast.File, "carbon forecast CSV policies plan with instead of the data")
+	fs.StringVar(&exp.Regions, "regions", exp.Regions, "regions the spatial policies route jobs to as name[:capacity[:latency[:transfer_g]]], e.g. CAISO:4:50ms:0.5,ERCOT,MISO,NYISO")
+	fs.BoolVar(&exp.Baseline, "baseline", exp.Baseline, "also run the offline oracle on the same workload as a carbon lower bound")
+}
+
+// experimentOptions describe the experiments a command runs, either from flags
+// alone or from a config file with flags overriding individual fields.
+type experimentOptions struct {
+	configPath string
+	exp        config.Experiment
+	register   func(fs *flag.FlagSet, exp *config.Experiment)
+	fs         *flag.FlagSet
+}
+
+func newExperimentOptions(fs *flag.FlagSet, register func(fs *flag.FlagSet, exp *config.Experiment)) *experimentOptions {
+	opts := &experimentOptions{
+		exp:      config.Default(),
+		register: register,
+		fs:       fs,
+	}
+	fs.StringVar(&opts.configPath, "config", "", "JSON experiment config; flags given alongside it override its fields")
+	register(fs, &opts.exp)
+	return opts
+}
+
+// experiments returns every experiment described by the flags and config,
+// with grids expanded.
+func (o *experimentOptions) experiments() ([]config.Experiment, error) {
+	exp := o.exp
+	if o.configPath != "" {
+		loaded, err := config.Load(o.configPath)
+		if err != nil {
+			return nil, err
+		}
+		// Re-apply the flags the user gave explicitly on top of the file
+		overrides := flag.NewFlagSet("overrides", flag.ContinueOnError)
+		o.register(overrides, loaded)
+		var setErr error
+		o.fs.Visit(func(f *flag.Flag) {
+			if overrides.Lookup(f.Name) != nil && setErr == nil {
+				setErr = overrides.Set(f.Name, f.Value.String())
+			}
+		})
+		if setErr != nil {
+			return nil, setErr
+		}
+		exp = *loaded
+	}
+	return exp.Expand()
+}
+
+// outputOptions select how results are written.
+type outputOptions struct {
+	format string
+	path   string
+}
+
+func (o *outputOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", "text", "results format: text, json or csv")
+	fs.StringVar(&o.path, "output", "-", "file to write results to, - for stdout")
+}
+
+func (o *outputOptions) validate() error {
+	switch o.format {
+	case "text", "json", "csv":
+		return nil
+	default:
+		return usageErrorf("invalid format %q, please choose text, json, or csv", o.format)
+	}
+}
+
+// runOutput pairs the results of an experiment with the resolved
+// configuration that produced them.
+type runOutput struct {
+	Config  config.Experiment `json:"config"`
+	Results any               `json:"results"`
+
+	rows []*simulator.Results // One CSV row per run
+	text fmt.Stringer         // Text rendering of the results
+}
+
+// write emits every output in the requested format, echoing the resolved
+// configuration alongside the results for provenance.
+func (o *outputOptions) write(outputs []runOutput) error {
+	var out io.Writer = os.Stdout
+	if o.path != "-" {
+		outFile, err := os.Create(o.path)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %w", err)
+		}
+		defer outFile.Close()
+		out = outFile
+	}
+	switch o.format {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if len(outputs) == 1 {
+			return encoder.Encode(outputs[0])
+		}
+		return encoder.Encode(outputs)
+	case "csv":
+		return writeOutputsCSV(out, outputs)
+	default:
+		for _, output := range outputs {
+			configJSON, err := json.MarshalIndent(output.Config, "", "  ")
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(out, "Configuration:\n%s\n%s\n", configJSON, output.text); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// writeOutputsCSV writes one row per run, prefixed by the flattened
+// configuration under config.* columns.
+func writeOutputsCSV(out io.Writer, outputs []runOutput) error {
+	configKeys := make([]string, 0)
+	results := make([]*simulator.Results, 0, len(outputs))
+	flattened := make([]map[string]string, len(outputs))
+	for i, output := range outputs {
+		flat, err := output.Config.Flatten()
+		if err != nil {
+			return err
+		}
+		flattened[i] = flat
+		for key := range flat {
+			if !slices.Contains(configKeys, key) {
+				configKeys = append(configKeys, key)
+			}
+		}
+		results = append(results, output.rows...)
+	}
+	slices.Sort(configKeys)
+	columns := simulator.NewCSVColumns(results)
+	header := make([]string, 0, len(configKeys))
+	for _, key := range configKeys {
+		header = append(header, "config."+key)
+	}
+	rows := [][]string{append(header, columns.Header()...)}
+	for i, output := range outputs {
+		prefix := make([]string, len(configKeys))
+		for j, key := range configKeys {
+			prefix[j] = flattened[i][key]
+		}
+		for _, result := range output.rows {
+			rows = append(rows, append(slices.Clone(prefix), result.CSVRecord(columns)...))
+		}
+	}
+	writer := csv.NewWriter(out)
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("error writing CSV: %w", err)
+	}
+	return nil
+}
+
+// parseFlags parses a subcommand's flags and rejects stray positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{msg: err.Error()}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"simulator/pkg/config"
+	"testing"
+)
+
+// experimentConfig sets a field to something other than its flag default
+// wherever the tests below check precedence.
+const experimentConfig = `{
+	"region": "CAISO",
+	"slo": "6hr",
+	"workload": {"name": "poisson", "jobs": 500, "rate": 20},
+	"policy": {"name": "temporal", "model": "small"},
+	"cluster": {"capacity": 4, "slots": {"small": 1, "large": 4}},
+	"baseline": true,
+	"replications": 5,
+	"grid": {"policy.safeguard_sd": [0, 1]}
+}`
+
+func TestExperimentOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiment.json")
+	if err := os.WriteFile(path, []byte(experimentConfig), 0644); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	tests := []struct {
+		name  string
+		args  []string
+		check func(t *testing.T, exp config.Experiment)
+	}{
+		{
+			name: "flags alone",
+			args: []string{"-region", "MISO", "-slo", "12hr", "-policy", "fifo", "-model", "large"},
+			check: func(t *testing.T, exp config.Experiment) {
+				if exp.Region != "MISO" || exp.SLO != "12hr" || exp.Policy.Name != "fifo" || exp.Workload.Jobs != 1000000 {
+					t.Errorf("experiment is %+v, want MISO, 12hr and fifo with the default jobs", exp)
+				}
+			},
+		},
+		{
+			name: "config alone",
+			args: []string{"-config", path},
+			check: func(t *testing.T, exp config.Experiment) {
+				if exp.SLO != "6hr" || exp.Workload.Jobs != 500 || exp.Cluster.Capacity != 4 || exp.Cluster.Slots["large"] != 4 {
+					t.Errorf("experiment is %+v, want the config's values", exp)
+				}
+			},
+		},
+		{
+			name: "flag overrides config",
+			args: []string{"-config", path, "-slo", "12hr", "-capacity", "8"},
+			check: func(t *testing.T, exp config.Experiment) {
+				if exp.SLO != "12hr" || exp.Cluster.Capacity != 8 {
+					t.Errorf("SLO %s and capacity %d, want the flags' 12hr and 8", exp.SLO, exp.Cluster.Capacity)
+				}
+				if exp.Workload.Name != "poisson" || exp.Workload.Jobs != 500 || exp.Policy.Model != "small" {
+					t.Errorf("flags not given changed the config's workload %+v or policy %+v", exp.Workload, exp.Policy)
+				}
+			},
+		},
+		{
+			name: "flag set to its default overrides config",
+			args: []string{"-config", path, "-jobs", "1000000", "-baseline=false", "-replications", "0"},
+			check: func(t *testing.T, exp config.Experiment) {
+				if exp.Workload.Jobs != 1000000 || exp.Baseline || exp.Replications != 0 {
+					t.Errorf("jobs %d, baseline %v and replications %d, want the flags' 1000000, false and 0", exp.Workload.Jobs, exp.Baseline, exp.Replications)
+				}
+			},
+		},
+		{
+			name: "custom flag overrides config",
+			args: []string{"-config", path, "-slots", "small=2", "-rate-profile", "1,2"},
+			check: func(t *testing.T, exp config.Experiment) {
+				if len(exp.Cluster.Slots) != 1 || exp.Cluster.Slots["small"] != 2 {
+					t.Errorf("slots are %v, want only small=2", exp.Cluster.Slots)
+				}
+				if len(exp.Workload.RateProfile) != 2 || exp.Workload.RateProfile[1] != 2 {
+					t.Errorf("rate profile is %v, want 1,2", exp.Workload.RateProfile)
+				}
+			},
+		},
+		{
+			name: "flag paths stay relative to the working directory",
+			args: []string{"-config", path, "-data", "carbon.csv"},
+			check: func(t *testing.T, exp config.Experiment) {
+				if exp.DataFile != "carbon.csv" {
+					t.Errorf("data file is %s, want carbon.csv as given", exp.DataFile)
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("sweep", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			opts := newExperimentOptions(fs, registerSweepFlags)
+			if err := fs.Parse(test.args); err != nil {
+				t.Fatalf("parsing flags: %v", err)
+			}
+			experiments, err := opts.experiments()
+			if err != nil {
+				t.Fatalf("experiments: %v", err)
+			}
+			for _, exp := range experiments {
+				test.check(t, exp)
+			}
+		})
+	}
+}
+
+func TestExperimentOptionsExpandsAfterOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiment.json")
+	if err := os.WriteFile(path, []byte(experimentConfig), 0644); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	fs := flag.NewFlagSet("sweep", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	opts := newExperimentOptions(fs, registerSweepFlags)
+	if err := fs.Parse([]string{"-config", path, "-safeguard-sd", "3", "-model", "large"}); err != nil {
+		t.Fatalf("parsing flags: %v", err)
+	}
+	experiments, err := opts.experiments()
+	if err != nil {
+		t.Fatalf("experiments: %v", err)
+	}
+	// Two grid points, each followed by its oracle baseline
+	if len(experiments) != 4 {
+		t.Fatalf("expanded to %d experiments, want 4", len(experiments))
+	}
+	for i, exp := range experiments {
+		if want := float64(i / 2); exp.Policy.Name != "oracle" && exp.Policy.SafeguardSD != want {
+			t.Errorf("experiment %d has safeguard %f, want the grid's %f over the flag", i, exp.Policy.SafeguardSD, want)
+		}
+		if exp.Policy.Model != "large" {
+			t.Errorf("experiment %d uses model %s, want the flag's large", i, exp.Policy.Model)
+		}
+	}
+}
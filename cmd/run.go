@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"simulator/pkg/experiment"
+	"simulator/pkg/simulator"
+)
+
+func runCommand(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	opts := newExperimentOptions(fs, registerExperimentFlags)
+	var output outputOptions
+	var tracePath, traceFormat string
+	output.register(fs)
+	fs.StringVar(&tracePath, "trace", "", "file to write a per-job trace to, empty to disable")
+	fs.StringVar(&traceFormat, "trace-format", "csv", "per-job trace format: csv or jsonl")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := output.validate(); err != nil {
+		return err
+	}
+	if traceFormat != "csv" && traceFormat != "jsonl" {
+		return usageErrorf("invalid trace format %q, please choose csv or jsonl", traceFormat)
+	}
+	experiments, err := opts.experiments()
+	if err != nil {
+		return err
+	}
+	if tracePath != "" && len(experiments) > 1 {
+		return usageErrorf("a per-job trace can only be written for a single experiment, the config describes %d", len(experiments))
+	}
+
+	outputs := make([]runOutput, 0, len(experiments))
+	for _, exp := range experiments {
+		cfg, err := exp.Resolve()
+		if err != nil {
+			return err
+		}
+		log.Printf("Running %s with seed %d", exp.Name, cfg.Seed)
+		runOpts := experiment.RunOptions{Logger: log.New(os.Stderr, "", log.LstdFlags)}
+		if tracePath != "" {
+			traceFile, err := os.Create(tracePath)
+			if err != nil {
+				return fmt.Errorf("error creating trace file: %w", err)
+			}
+			defer traceFile.Close()
+			if traceFormat == "jsonl" {
+				runOpts.Trace = simulator.NewJSONLTraceWriter(traceFile)
+			} else {
+				runOpts.Trace = simulator.NewCSVTraceWriter(traceFile)
+			}
+		}
+		simElement, results, err := experiment.Run(cfg, cfg.Seed, runOpts)
+		if err != nil {
+			return fmt.Errorf("error running simulation: %w", err)
+		}
+		log.Println("Simulation complete.")
+		outputs = append(outputs, runOutput{
+			Config:  exp,
+			Results: results,
+			rows:    []*simulator.Results{results},
+			text:    simElement,
+		})
+	}
+	return output.write(outputs)
+}
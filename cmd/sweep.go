@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"simulator/pkg/config"
+	"simulator/pkg/experiment"
+)
+
+// defaultReplications is used when neither the flags nor the config set a count.
+const defaultReplications = 10
+
+func registerSweepFlags(fs *flag.FlagSet, exp *config.Experiment) {
+	registerExperimentFlags(fs, exp)
+	fs.IntVar(&exp.Replications, "replications", exp.Replications, fmt.Sprintf("number of replications, run with seeds seed, seed+1, ... (default %d)", defaultReplications))
+}
+
+func sweepCommand(args []string) error {
+	fs := flag.NewFlagSet("sweep", flag.ContinueOnError)
+	opts := newExperimentOptions(fs, registerSweepFlags)
+	var output outputOptions
+	var parallel int
+	output.register(fs)
+	fs.IntVar(&parallel, "parallel", 0, "maximum replications to run at once, 0 uses every CPU")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := output.validate(); err != nil {
+		return err
+	}
+	experiments, err := opts.experiments()
+	if err != nil {
+		return err
+	}
+
+	outputs := make([]runOutput, 0, len(experiments))
+	for _, exp := range experiments {
+		if exp.Replications == 0 {
+			exp.Replications = defaultReplications
+		}
+		cfg, err := exp.Resolve()
+		if err != nil {
+			return err
+		}
+		log.Printf("Running %d replications of %s starting from seed %d", exp.Replications, exp.Name, cfg.Seed)
+		report, err := experiment.Replicate(cfg, exp.Replications, parallel)
+		if err != nil {
+			return fmt.Errorf("error running replications: %w", err)
+		}
+		outputs = append(outputs, runOutput{
+			Config:  exp,
+			Results: report,
+			rows:    report.Replications,
+			text:    report,
+		})
+	}
+	return output.write(outputs)
+}
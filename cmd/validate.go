@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+)
+
+func validateCommand(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	opts := newExperimentOptions(fs, registerSweepFlags)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	experiments, err := opts.experiments()
+	if err != nil {
+		return err
+	}
+	for _, exp := range experiments {
+		cfg, err := exp.Resolve()
+		if err != nil {
+			return fmt.Errorf("%s: %w", exp.Name, err)
+		}
+		configJSON, err := json.MarshalIndent(exp, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Configuration valid: %d jobs over %s to %s\n%s\n", cfg.JobInfo.NumJobs, cfg.Loader.StartDate(), cfg.Loader.EndDate(), configJSON)
+	}
+	return nil
+}
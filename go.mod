@@ -1,5 +1,3 @@
 module simulator
 
 go 1.23.4
-
-require github.com/gocarina/gocsv v0.0.0-20240520201108-78e41c74b4b1
@@ -0,0 +1,803 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"simulator/pkg/directory"
+	"simulator/pkg/environment"
+	"simulator/pkg/experiment"
+	"simulator/pkg/forecast"
+	"simulator/pkg/loader"
+	"simulator/pkg/simulator"
+	"simulator/pkg/simulator/policies"
+	"simulator/pkg/workload"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError reports an experiment that cannot be run as described.
+type ValidationError struct {
+	msg string
+}
+
+func (v *ValidationError) Error() string {
+	return v.msg
+}
+
+func invalidf(format string, args ...any) error {
+	return &ValidationError{msg: fmt.Sprintf(format, args...)}
+}
+
+// region describes the carbon data of a grid operator.
+type region struct {
+	fileName string
+	timeZone string // IANA zone of the operator's local demand
+}
+
+var regions = map[string]region{
+	"CAISO": {"CAISO.csv", "America/Los_Angeles"},
+	"ERCOT": {"ERCOT.csv", "America/Chicago"},
+	"MISO":  {"MISO.csv", "America/Chicago"},
+	"NYISO": {"NYISO.csv", "America/New_York"},
+}
+
+var policyNames = []string{"fifo", "temporal", "interruptible", "spatial", "spatioTemporal", "modelSelection", "hybridSelection", "oracle"}
+
+// routedRegion is one entry of Experiment.Regions.
+type routedRegion struct {
+	name           string
+	capacity       int
+	latency        time.Duration
+	transferCarbon float64
+}
+
+// parseRegions parses comma separated name[:capacity[:latency[:transfer_g]]]
+// entries, where capacity 0 leaves the region unbounded.
+func parseRegions(spec string) ([]routedRegion, error) {
+	entries := strings.Split(spec, ",")
+	routed := make([]routedRegion, len(entries))
+	for i, entry := range entries {
+		fields := strings.Split(entry, ":")
+		if len(fields) > 4 {
+			return nil, invalidf("invalid region %q, please use name[:capacity[:latency[:transfer_g]]]", entry)
+		}
+		region := routedRegion{name: fields[0]}
+		if _, ok := regions[region.name]; !ok {
+			return nil, invalidf("invalid region %q, please choose CAISO, ERCOT, MISO, or NYISO", region.name)
+		}
+		for _, previous := range routed[:i] {
+			if previous.name == region.name {
+				return nil, invalidf("duplicate region %s", region.name)
+			}
+		}
+		var err error
+		if len(fields) > 1 {
+			if region.capacity, err = strconv.Atoi(fields[1]); err != nil || region.capacity < 0 {
+				return nil, invalidf("invalid capacity %q for region %s", fields[1], region.name)
+			}
+		}
+		if len(fields) > 2 {
+			if region.latency, err = time.ParseDuration(fields[2]); err != nil || region.latency < 0 {
+				return nil, invalidf("invalid latency %q for region %s", fields[2], region.name)
+			}
+		}
+		if len(fields) > 3 {
+			if region.transferCarbon, err = strconv.ParseFloat(fields[3], 64); err != nil || region.transferCarbon < 0 {
+				return nil, invalidf("invalid transfer carbon %q for region %s", fields[3], region.name)
+			}
+		}
+		routed[i] = region
+	}
+	return routed, nil
+}
+
+// Default returns an experiment with every optional field at its default.
+func Default() Experiment {
+	return Experiment{
+		DataDir:    filepath.Join("data", "collected"),
+		ModelsFile: filepath.Join("cmd", "AIModels.json"),
+		Workload: WorkloadConfig{
+			Name:          "random",
+			Jobs:          1000000,
+			SpikeFraction: workload.DefaultSpikeFraction,
+		},
+	}
+}
+
+// Load reads an experiment from a JSON file. Fields missing from the file
+// keep their defaults, and relative paths in the file are resolved against
+// the file's directory.
+func Load(path string) (*Experiment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+	var given Experiment
+	if err := decodeStrict(data, &given); err != nil {
+		return nil, invalidf("error parsing config %s: %v", path, err)
+	}
+	exp := Default()
+	if err := decodeStrict(data, &exp); err != nil {
+		return nil, invalidf("error parsing config %s: %v", path, err)
+	}
+	baseDir := filepath.Dir(path)
+	for _, field := range []struct {
+		given string
+		value *string
+	}{
+		{given.DataDir, &exp.DataDir},
+		{given.DataFile, &exp.DataFile},
+		{given.ModelsFile, &exp.ModelsFile},
+		{given.Workload.Arrivals, &exp.Workload.Arrivals},
+		{given.Forecast.File, &exp.Forecast.File},
+	} {
+		if field.given != "" && !filepath.IsAbs(field.given) {
+			*field.value = filepath.Join(baseDir, field.given)
+		}
+	}
+	return &exp, nil
+}
+
+func decodeStrict(data []byte, target any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+// Validate checks the experiment without touching the filesystem.
+func (e *Experiment) Validate() error {
+	if e.DataFile == "" {
+		if e.Region == "" {
+			return invalidf("either a region or a data file is required")
+		}
+		if _, ok := regions[e.Region]; !ok {
+			return invalidf("invalid region %q, please choose CAISO, ERCOT, MISO, or NYISO", e.Region)
+		}
+	}
+	if e.Regions != "" {
+		if _, err := parseRegions(e.Regions); err != nil {
+			return err
+		}
+	}
+	if err := e.Schema().Validate(); err != nil {
+		return invalidf("%v", err)
+	}
+	if e.DataQuality.Resample != "" {
+		if interval, err := time.ParseDuration(e.DataQuality.Resample); err != nil || interval <= 0 {
+			return invalidf("invalid resampling interval %q", e.DataQuality.Resample)
+		}
+	}
+	if e.DataQuality.Interpolation != "" && !slices.Contains(loader.Interpolations, e.DataQuality.Interpolation) {
+		return invalidf("invalid interpolation %q, please choose %s", e.DataQuality.Interpolation, strings.Join(loader.Interpolations, ", "))
+	}
+	if e.DataQuality.MaxGap != "" {
+		if maxGap, err := time.ParseDuration(e.DataQuality.MaxGap); err != nil || maxGap < 0 {
+			return invalidf("invalid maximum gap %q", e.DataQuality.MaxGap)
+		}
+	}
+	for _, signal := range []string{e.Signal.Decision, e.Signal.Accounting} {
+		if signal != "" && !slices.Contains(loader.Signals, signal) {
+			return invalidf("invalid signal %q, please choose %s", signal, strings.Join(loader.Signals, ", "))
+		}
+	}
+	for _, zone := range []string{e.TimeZone, e.Workload.TimeZone} {
+		if _, err := time.LoadLocation(zone); err != nil {
+			return invalidf("invalid time zone %q: %v", zone, err)
+		}
+	}
+	if _, err := workload.ParseSLOMix(e.SLO); err != nil {
+		return invalidf("%v", err)
+	}
+	if !slices.Contains(workload.WorkloadPolicies, e.Workload.Name) {
+		return invalidf("invalid workload %q, please choose %s", e.Workload.Name, strings.Join(workload.WorkloadPolicies, ", "))
+	}
+	if e.Workload.Jobs <= 0 {
+		return invalidf("number of jobs must be positive, got %d", e.Workload.Jobs)
+	}
+	if e.Workload.SpikeFraction < 0 || e.Workload.SpikeFraction > 1 {
+		return invalidf("spike fraction must be between 0 and 1, got %f", e.Workload.SpikeFraction)
+	}
+	if e.Workload.SpikeWindows != "" {
+		if _, err := workload.ParseHourWindows(e.Workload.SpikeWindows); err != nil {
+			return invalidf("%v", err)
+		}
+	}
+	if e.Workload.SpikeDays != "" && !slices.Contains(workload.SpikeDays, e.Workload.SpikeDays) {
+		return invalidf("invalid spike days %q, please choose %s", e.Workload.SpikeDays, strings.Join(workload.SpikeDays, ", "))
+	}
+	switch e.Workload.Name {
+	case "spike":
+		if e.Workload.SpikeWindows == "" && e.Workload.SpikeDays == "" {
+			return invalidf("the spike workload requires spike windows or spike days")
+		}
+	case "poisson", "nhpp":
+		if e.Workload.Rate <= 0 {
+			return invalidf("the %s workload requires a positive rate, got %f", e.Workload.Name, e.Workload.Rate)
+		}
+	case "mmpp":
+		if _, err := workload.ParseMMPPStates(e.Workload.MMPP); err != nil {
+			return invalidf("%v", err)
+		}
+	case "mixture":
+		if _, err := workload.ParseMixture(e.Workload.Mixture); err != nil {
+			return invalidf("%v", err)
+		}
+	}
+	if e.Workload.Name == "nhpp" && len(e.Workload.RateProfile) != 24 && len(e.Workload.RateProfile) != 168 {
+		return invalidf("the nhpp workload requires 24 hourly or 168 weekly rate multipliers, got %d", len(e.Workload.RateProfile))
+	}
+	if e.Workload.Name == "trace" && e.Workload.Arrivals == "" {
+		return invalidf("the trace workload requires an arrival log")
+	}
+	if e.Workload.ArrivalsFormat != "" && !slices.Contains(workload.TraceFormats, e.Workload.ArrivalsFormat) {
+		return invalidf("invalid arrival log format %q, please choose %s", e.Workload.ArrivalsFormat, strings.Join(workload.TraceFormats, ", "))
+	}
+	if e.Workload.Align != "" && !slices.Contains(workload.TraceAlignments, e.Workload.Align) {
+		return invalidf("invalid alignment %q, please choose %s", e.Workload.Align, strings.Join(workload.TraceAlignments, ", "))
+	}
+	switch e.Policy.Name {
+	case "fifo", "temporal", "interruptible", "spatial", "spatioTemporal":
+		if e.Policy.Model == "" {
+			return invalidf("policy %s requires a model", e.Policy.Name)
+		}
+		if (e.Policy.Name == "spatial" || e.Policy.Name == "spatioTemporal") && e.Regions == "" {
+			return invalidf("policy %s requires regions to route jobs to", e.Policy.Name)
+		}
+	case "modelSelection", "hybridSelection":
+		if e.Policy.Accuracy <= 0 || e.Policy.Accuracy > 1 {
+			return invalidf("policy %s requires an accuracy between 0 and 1", e.Policy.Name)
+		}
+	case "oracle":
+		if e.Policy.Accuracy < 0 || e.Policy.Accuracy > 1 {
+			return invalidf("policy oracle requires an accuracy between 0 and 1")
+		}
+	case "":
+		return invalidf("a policy is required")
+	default:
+		return invalidf("invalid policy %q, please choose %s", e.Policy.Name, strings.Join(policyNames, ", "))
+	}
+	if _, err := policies.ParseAccuracyConstraint(e.Policy.AccuracyConstraint, e.Policy.Accuracy); err != nil {
+		return invalidf("%v", err)
+	}
+	if e.Policy.CheckpointOverhead != "" {
+		if overhead, err := time.ParseDuration(e.Policy.CheckpointOverhead); err != nil || overhead < 0 {
+			return invalidf("invalid checkpoint overhead %q", e.Policy.CheckpointOverhead)
+		}
+	}
+	if e.Policy.SafeguardSD < 0 {
+		return invalidf("safeguard standard deviations cannot be negative, got %f", e.Policy.SafeguardSD)
+	}
+	if e.Cluster.Capacity < 0 {
+		return invalidf("capacity cannot be negative, got %d", e.Cluster.Capacity)
+	}
+	for name, slots := range e.Cluster.Slots {
+		if slots <= 0 {
+			return invalidf("invalid slot count %d for model %s", slots, name)
+		}
+	}
+	if e.Forecast.Error < 0 || e.Forecast.Growth < 0 {
+		return invalidf("forecast error and its growth cannot be negative, got %f and %f", e.Forecast.Error, e.Forecast.Growth)
+	}
+	if e.Forecast.Correlation < 0 || e.Forecast.Correlation > 1 {
+		return invalidf("forecast error correlation must be between 0 and 1, got %f", e.Forecast.Correlation)
+	}
+	if e.Forecast.Lag != "" {
+		if lag, err := time.ParseDuration(e.Forecast.Lag); err != nil || lag < 0 {
+			return invalidf("invalid forecast lag %q", e.Forecast.Lag)
+		}
+	}
+	if e.Forecast.Horizon != "" {
+		if horizon, err := time.ParseDuration(e.Forecast.Horizon); err != nil || horizon <= 0 {
+			return invalidf("invalid forecast horizon %q", e.Forecast.Horizon)
+		}
+	}
+	if e.Replications < 0 {
+		return invalidf("number of replications cannot be negative, got %d", e.Replications)
+	}
+	return nil
+}
+
+// Expand returns one experiment per combination of grid values, in a stable
+// order. Experiments without a grid expand to themselves.
+func (e *Experiment) Expand() ([]Experiment, error) {
+	base := *e
+	base.Grid = nil
+	keys := slices.Sorted(maps.Keys(e.Grid))
+	expanded := []Experiment{base}
+	for _, key := range keys {
+		values := e.Grid[key]
+		if len(values) == 0 {
+			return nil, invalidf("grid entry %s has no values", key)
+		}
+		next := make([]Experiment, 0, len(expanded)*len(values))
+		for _, exp := range expanded {
+			for _, value := range values {
+				updated, err := exp.with(key, value)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, updated)
+			}
+		}
+		expanded = next
+	}
+	if len(keys) > 0 {
+		for i := range expanded {
+			labels := make([]string, len(keys))
+			flat, err := expanded[i].Flatten()
+			if err != nil {
+				return nil, err
+			}
+			for j, key := range keys {
+				labels[j] = key + "=" + flat[key]
+			}
+			expanded[i].Name = strings.TrimSpace(e.Name + " [" + strings.Join(labels, ", ") + "]")
+		}
+	}
+	if e.Baseline && e.Policy.Name != "oracle" {
+		withBaselines := make([]Experiment, 0, 2*len(expanded))
+		for _, exp := range expanded {
+			// Both runs need the same seed to see the same workload
+			if exp.Seed == 0 {
+				exp.Seed = time.Now().UnixNano()
+			}
+			withBaselines = append(withBaselines, exp, exp.OracleBaseline())
+		}
+		expanded = withBaselines
+	}
+	return expanded, nil
+}
+
+// OracleBaseline returns the experiment with its policy replaced by the
+// offline oracle under the same model or accuracy constraint, giving a lower
+// bound on the carbon the online policy could achieve.
+func (e Experiment) OracleBaseline() Experiment {
+	baseline := e
+	baseline.Name = strings.TrimSpace(e.Name + " [oracle baseline]")
+	baseline.Baseline = false
+	baseline.Policy = PolicyConfig{Name: "oracle"}
+	switch e.Policy.Name {
+	case "fifo", "temporal", "interruptible", "spatial", "spatioTemporal":
+		baseline.Policy.Model = e.Policy.Model
+	default:
+		baseline.Policy.Accuracy = e.Policy.Accuracy
+	}
+	return baseline
+}
+
+// with returns a copy of the experiment with the field at the dotted path set.
+func (e Experiment) with(path string, value any) (Experiment, error) {
+	fields, err := e.toMap()
+	if err != nil {
+		return Experiment{}, err
+	}
+	parts := strings.Split(path, ".")
+	current := fields
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := current[part].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			current[part] = child
+		}
+		current = child
+	}
+	current[parts[len(parts)-1]] = value
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return Experiment{}, err
+	}
+	var updated Experiment
+	if err := decodeStrict(data, &updated); err != nil {
+		return Experiment{}, invalidf("invalid grid entry %s: %v", path, err)
+	}
+	return updated, nil
+}
+
+func (e Experiment) toMap() (map[string]any, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]any)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// Flatten returns every configured field keyed by its dotted path, which is
+// how the configuration is echoed next to tabular results.
+func (e Experiment) Flatten() (map[string]string, error) {
+	fields, err := e.toMap()
+	if err != nil {
+		return nil, err
+	}
+	delete(fields, "grid")
+	flat := make(map[string]string)
+	flattenInto(flat, "", fields)
+	return flat, nil
+}
+
+func flattenInto(flat map[string]string, prefix string, fields map[string]any) {
+	for key, value := range fields {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if child, ok := value.(map[string]any); ok {
+			flattenInto(flat, path, child)
+			continue
+		}
+		flat[path] = fmt.Sprint(value)
+	}
+}
+
+func (e *Experiment) DataPath() (string, error) {
+	if e.DataFile != "" {
+		return e.DataFile, nil
+	}
+	dataRegion, ok := regions[e.Region]
+	if !ok {
+		return "", invalidf("invalid region %q, please choose CAISO, ERCOT, MISO, or NYISO", e.Region)
+	}
+	return filepath.Join(e.DataDir, dataRegion.fileName), nil
+}
+
+// DataLocation returns the local time zone of the carbon data: the configured
+// zone, else the region's, else UTC for data files without a region.
+func (e *Experiment) DataLocation() (*time.Location, error) {
+	zone := e.TimeZone
+	if zone == "" && e.DataFile == "" {
+		zone = regions[e.Region].timeZone
+	}
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, invalidf("invalid time zone %q: %v", zone, err)
+	}
+	return location, nil
+}
+
+// WorkloadLocation returns the zone workload hour windows are interpreted in,
+// the data's zone unless configured otherwise.
+func (e *Experiment) WorkloadLocation() (*time.Location, error) {
+	if e.Workload.TimeZone == "" {
+		return e.DataLocation()
+	}
+	location, err := time.LoadLocation(e.Workload.TimeZone)
+	if err != nil {
+		return nil, invalidf("invalid time zone %q: %v", e.Workload.TimeZone, err)
+	}
+	return location, nil
+}
+
+// Schema returns the layout the carbon data files are read with.
+func (e *Experiment) Schema() loader.Schema {
+	return loader.Schema{
+		Provider:   e.DataSchema.Provider,
+		Format:     e.DataSchema.Format,
+		Records:    e.DataSchema.Records,
+		Time:       e.DataSchema.Time,
+		TimeLayout: e.DataSchema.TimeLayout,
+		Intensity:  e.DataSchema.Intensity,
+		Marginal:   e.DataSchema.Marginal,
+		Unit:       loader.Unit(e.DataSchema.Unit),
+	}
+}
+
+func (e *Experiment) LoadData() (*loader.Loader, error) {
+	dataPath, err := e.DataPath()
+	if err != nil {
+		return nil, err
+	}
+	location, err := e.DataLocation()
+	if err != nil {
+		return nil, err
+	}
+	dataLoader := loader.NewLoaderWithSchema(dataPath, e.Schema())
+	if dataLoader == nil {
+		return nil, fmt.Errorf("could not load carbon data from %s", dataPath)
+	}
+	dataLoader.SetLocation(location)
+	return e.prepareData(dataLoader)
+}
+
+// prepareData applies the data quality settings to freshly loaded data. Gaps
+// are checked before resampling, which would otherwise fill them.
+func (e *Experiment) prepareData(dataLoader *loader.Loader) (*loader.Loader, error) {
+	if e.DataQuality.Strict {
+		maxGap := time.Duration(0)
+		if e.DataQuality.MaxGap != "" {
+			var err error
+			if maxGap, err = time.ParseDuration(e.DataQuality.MaxGap); err != nil || maxGap < 0 {
+				return nil, invalidf("invalid maximum gap %q", e.DataQuality.MaxGap)
+			}
+		}
+		if err := dataLoader.CheckGaps(maxGap); err != nil {
+			return nil, fmt.Errorf("strict data quality: %w", err)
+		}
+	}
+	if e.DataQuality.Resample == "" {
+		return dataLoader, nil
+	}
+	interval, err := time.ParseDuration(e.DataQuality.Resample)
+	if err != nil || interval <= 0 {
+		return nil, invalidf("invalid resampling interval %q", e.DataQuality.Resample)
+	}
+	return dataLoader.Resample(interval, e.DataQuality.Interpolation)
+}
+
+// Signals returns the loaders of the decision and accounting signals of the
+// data. The accounting loader is nil when both signals are the same.
+func (e *Experiment) Signals(dataLoader *loader.Loader) (*loader.Loader, *loader.Loader, error) {
+	decision, err := dataLoader.Signal(e.Signal.Decision)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e.Signal.Accounting == e.Signal.Decision {
+		return decision, nil, nil
+	}
+	accounting, err := dataLoader.Signal(e.Signal.Accounting)
+	if err != nil {
+		return nil, nil, err
+	}
+	if accounting == decision {
+		return decision, nil, nil
+	}
+	return decision, accounting, nil
+}
+
+// ForecastModel loads the forecast policies plan with.
+func (e *Experiment) ForecastModel() (forecast.Model, error) {
+	model := forecast.Model{
+		Error:       e.Forecast.Error,
+		Growth:      e.Forecast.Growth,
+		Correlation: e.Forecast.Correlation,
+	}
+	if e.Forecast.Lag != "" {
+		lag, err := time.ParseDuration(e.Forecast.Lag)
+		if err != nil || lag < 0 {
+			return forecast.Model{}, invalidf("invalid forecast lag %q", e.Forecast.Lag)
+		}
+		model.Lag = lag
+	}
+	if e.Forecast.Horizon != "" {
+		horizon, err := time.ParseDuration(e.Forecast.Horizon)
+		if err != nil || horizon <= 0 {
+			return forecast.Model{}, invalidf("invalid forecast horizon %q", e.Forecast.Horizon)
+		}
+		model.Horizon = horizon
+	}
+	if e.Forecast.File != "" {
+		model.Source = loader.NewLoaderWithSchema(e.Forecast.File, e.Schema())
+		if model.Source == nil {
+			return forecast.Model{}, fmt.Errorf("could not load forecast data from %s", e.Forecast.File)
+		}
+		// Forecasts are of the signal policies optimise against
+		source, err := model.Source.Signal(e.Signal.Decision)
+		if err != nil {
+			return forecast.Model{}, err
+		}
+		model.Source = source
+	}
+	return model, nil
+}
+
+// LoadRegions loads the carbon data of every region jobs may be routed to.
+// The home region reuses home and homeAccounting, the signals already loaded
+// for the experiment.
+func (e *Experiment) LoadRegions(home, homeAccounting *loader.Loader) ([]environment.Region, error) {
+	if e.Regions == "" {
+		return nil, nil
+	}
+	routed, err := parseRegions(e.Regions)
+	if err != nil {
+		return nil, err
+	}
+	loaded := make([]environment.Region, len(routed))
+	for i, region := range routed {
+		dataLoader, accounting := home, homeAccounting
+		if region.name != e.Region || e.DataFile != "" {
+			dataPath := filepath.Join(e.DataDir, regions[region.name].fileName)
+			if dataLoader = loader.NewLoaderWithSchema(dataPath, e.Schema()); dataLoader == nil {
+				return nil, fmt.Errorf("could not load carbon data for region %s from %s", region.name, dataPath)
+			}
+			location, err := time.LoadLocation(regions[region.name].timeZone)
+			if err != nil {
+				return nil, err
+			}
+			dataLoader.SetLocation(location)
+			if dataLoader, err = e.prepareData(dataLoader); err != nil {
+				return nil, err
+			}
+			if dataLoader, accounting, err = e.Signals(dataLoader); err != nil {
+				return nil, fmt.Errorf("region %s: %w", region.name, err)
+			}
+		}
+		loaded[i] = environment.Region{
+			Name:           region.name,
+			Loader:         dataLoader,
+			Accounting:     accounting,
+			Capacity:       region.capacity,
+			Latency:        region.latency,
+			TransferCarbon: region.transferCarbon,
+		}
+	}
+	return loaded, nil
+}
+
+func (e *Experiment) LoadModels() (*directory.Directory, error) {
+	modelDirectory := directory.NewDirectory(e.ModelsFile)
+	if modelDirectory == nil {
+		return nil, fmt.Errorf("could not load model definitions from %s", e.ModelsFile)
+	}
+	return modelDirectory, nil
+}
+
+func (e *Experiment) JobInfo() (workload.JobMetadata, error) {
+	slos, err := workload.ParseSLOMix(e.SLO)
+	if err != nil {
+		return workload.JobMetadata{}, invalidf("%v", err)
+	}
+	jobInfo := workload.NewJobInfo(slos.MaxDueTime(), e.Workload.Jobs, e.Workload.Name)
+	jobInfo.SLOs = slos
+	jobInfo.SpikeFraction = e.Workload.SpikeFraction
+	if e.Workload.SpikeWindows != "" {
+		if jobInfo.SpikeWindows, err = workload.ParseHourWindows(e.Workload.SpikeWindows); err != nil {
+			return workload.JobMetadata{}, invalidf("%v", err)
+		}
+	}
+	jobInfo.SpikeDays = e.Workload.SpikeDays
+	if jobInfo.Location, err = e.WorkloadLocation(); err != nil {
+		return workload.JobMetadata{}, err
+	}
+	jobInfo.ArrivalRate = e.Workload.Rate
+	jobInfo.RateProfile = e.Workload.RateProfile
+	if e.Workload.MMPP != "" {
+		if jobInfo.MMPPStates, err = workload.ParseMMPPStates(e.Workload.MMPP); err != nil {
+			return workload.JobMetadata{}, invalidf("%v", err)
+		}
+	}
+	if e.Workload.Mixture != "" {
+		if jobInfo.Mixture, err = workload.ParseMixture(e.Workload.Mixture); err != nil {
+			return workload.JobMetadata{}, invalidf("%v", err)
+		}
+	}
+	jobInfo.TracePath = e.Workload.Arrivals
+	jobInfo.TraceFormat = e.Workload.ArrivalsFormat
+	jobInfo.TraceAlign = e.Workload.Align
+	return jobInfo, nil
+}
+
+// NewPolicy builds the configured policy. It is used as an experiment
+// PolicyFactory so every replication gets a fresh policy.
+func (e *Experiment) NewPolicy(env *environment.Environment) (simulator.PolicyInterface, error) {
+	switch e.Policy.Name {
+	case "fifo", "temporal", "interruptible", "spatial", "spatioTemporal":
+		model, err := env.Directory.GetModelDefinition(e.Policy.Model)
+		if err != nil {
+			return nil, invalidf("%v", err)
+		}
+		switch e.Policy.Name {
+		case "fifo":
+			return policies.NewFIFO(env, model), nil
+		case "temporal":
+			return policies.NewTemporal(env, model, e.Policy.SafeguardSD), nil
+		case "spatial", "spatioTemporal":
+			return policies.NewSpatial(env, model, e.Policy.SafeguardSD, e.Policy.Name == "spatioTemporal"), nil
+		}
+		overhead := time.Duration(0)
+		if e.Policy.CheckpointOverhead != "" {
+			if overhead, err = time.ParseDuration(e.Policy.CheckpointOverhead); err != nil || overhead < 0 {
+				return nil, invalidf("invalid checkpoint overhead %q", e.Policy.CheckpointOverhead)
+			}
+		}
+		return policies.NewInterruptible(env, model, e.Policy.SafeguardSD, overhead), nil
+	case "modelSelection", "hybridSelection":
+		constraint, err := policies.ParseAccuracyConstraint(e.Policy.AccuracyConstraint, e.Policy.Accuracy)
+		if err != nil {
+			return nil, invalidf("%v", err)
+		}
+		if e.Policy.Name == "modelSelection" {
+			return policies.NewModelSelection(env, e.Policy.Accuracy, constraint), nil
+		}
+		return policies.NewHybridSelection(env, e.Policy.Accuracy, e.Policy.SafeguardSD, constraint), nil
+	case "oracle":
+		var model *directory.AIModelDefinition
+		if e.Policy.Model != "" {
+			definition, err := env.Directory.GetModelDefinition(e.Policy.Model)
+			if err != nil {
+				return nil, invalidf("%v", err)
+			}
+			model = definition
+		}
+		return policies.NewOracle(env, e.Policy.Accuracy, model, e.Cluster.Capacity, e.Cluster.Slots), nil
+	default:
+		return nil, invalidf("invalid policy %q, please choose %s", e.Policy.Name, strings.Join(policyNames, ", "))
+	}
+}
+
+// AccuracyConstraints returns the accuracy constraints whose violations are
+// reported: global, tenant and job, plus any window the policy enforces.
+func (e *Experiment) AccuracyConstraints() []string {
+	constraints := []string{"global", "tenant", "job"}
+	if e.Policy.AccuracyConstraint != "" && !slices.Contains(constraints, e.Policy.AccuracyConstraint) {
+		constraints = append(constraints, e.Policy.AccuracyConstraint)
+	}
+	return constraints
+}
+
+// Resolve validates the experiment, loads its inputs and returns a runnable
+// configuration. An unset seed is replaced by one drawn from the clock and
+// written back so the echoed configuration reproduces the run.
+func (e *Experiment) Resolve() (experiment.Config, error) {
+	if err := e.Validate(); err != nil {
+		return experiment.Config{}, err
+	}
+	jobInfo, err := e.JobInfo()
+	if err != nil {
+		return experiment.Config{}, err
+	}
+	dataLoader, err := e.LoadData()
+	if err != nil {
+		return experiment.Config{}, err
+	}
+	if dataLoader.EndDate().Add(-jobInfo.DueTime).Before(dataLoader.StartDate()) {
+		return experiment.Config{}, invalidf("carbon data spans less than the SLO of %v", jobInfo.DueTime)
+	}
+	dataLoader, accounting, err := e.Signals(dataLoader)
+	if err != nil {
+		return experiment.Config{}, err
+	}
+	forecastModel, err := e.ForecastModel()
+	if err != nil {
+		return experiment.Config{}, err
+	}
+	routedRegions, err := e.LoadRegions(dataLoader, accounting)
+	if err != nil {
+		return experiment.Config{}, err
+	}
+	modelDirectory, err := e.LoadModels()
+	if err != nil {
+		return experiment.Config{}, err
+	}
+	for name := range e.Cluster.Slots {
+		if _, err := modelDirectory.GetModelDefinition(name); err != nil {
+			return experiment.Config{}, invalidf("slot requirement for unknown model: %v", err)
+		}
+	}
+	if e.Seed == 0 {
+		e.Seed = time.Now().UnixNano()
+	}
+	cfg := experiment.Config{
+		Loader:              dataLoader,
+		Accounting:          accounting,
+		Directory:           modelDirectory,
+		JobInfo:             jobInfo,
+		Capacity:            e.Cluster.Capacity,
+		Slots:               e.Cluster.Slots,
+		Forecast:            forecastModel,
+		Regions:             routedRegions,
+		AccuracyTarget:      e.Policy.Accuracy,
+		AccuracyConstraints: e.AccuracyConstraints(),
+		NewPolicy:           e.NewPolicy,
+		Seed:                e.Seed,
+	}
+	// Build the policy once up front so bad policy options surface before simulating
+	env, err := environment.NewEnvironment(cfg.Loader, cfg.Directory, cfg.Seed)
+	if err != nil {
+		return experiment.Config{}, err
+	}
+	env.Regions = cfg.Regions
+	if _, err := e.NewPolicy(env); err != nil {
+		return experiment.Config{}, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,117 @@
+package config
+
+// Experiment is the declarative description of one experiment. When Grid is
+// set the experiment describes a family of experiments, one for every
+// combination of the listed values.
+type Experiment struct {
+	Name       string `json:"name,omitempty"`
+	Region     string `json:"region,omitempty"`      // CAISO, ERCOT, MISO or NYISO
+	DataDir    string `json:"data_dir,omitempty"`    // Directory holding <region>.csv files
+	DataFile   string `json:"data_file,omitempty"`   // Carbon data file, overrides Region and DataDir
+	ModelsFile string `json:"models_file,omitempty"` // AI model definitions
+	TimeZone   string `json:"time_zone,omitempty"`   // IANA zone of the data's region, defaults to the region's own
+
+	// Layout of the carbon data files, those of data/collected when empty
+	DataSchema  DataSchemaConfig  `json:"data_schema,omitempty"`
+	DataQuality DataQualityConfig `json:"data_quality,omitempty"`
+	Signal      SignalConfig      `json:"signal,omitempty"`
+
+	// Regions jobs may be routed to, as name[:capacity[:latency[:transfer_g]]]
+	// entries such as CAISO:4:50ms:0.5,ERCOT:2,MISO,NYISO. Empty for
+	// single-region runs.
+	Regions string `json:"regions,omitempty"`
+
+	SLO      string         `json:"slo"` // Duration, range or weighted classes, see workload.ParseSLOMix
+	Workload WorkloadConfig `json:"workload"`
+	Policy   PolicyConfig   `json:"policy"`
+	Cluster  ClusterConfig  `json:"cluster"`
+	Forecast ForecastConfig `json:"forecast"`
+
+	Seed         int64 `json:"seed"`                   // 0 picks a seed from the clock
+	Replications int   `json:"replications,omitempty"` // Used by sweeps
+	Baseline     bool  `json:"baseline,omitempty"`     // Also run the offline oracle on the same workload
+
+	// Grid maps dotted field paths, such as "policy.accuracy", to the values
+	// that field takes across the expanded experiments.
+	Grid map[string][]any `json:"grid,omitempty"`
+}
+
+type WorkloadConfig struct {
+	Name          string  `json:"name"`
+	Jobs          int     `json:"jobs"`
+	SpikeFraction float64 `json:"spike_fraction"`          // Share of jobs placed in the spike period
+	SpikeWindows  string  `json:"spike_windows,omitempty"` // Spike hours such as 5-12,17-20, overriding named spikes
+	SpikeDays     string  `json:"spike_days,omitempty"`    // all, weekday or weekend, overriding named spikes
+	TimeZone      string  `json:"time_zone,omitempty"`     // IANA zone of hour windows and rate profiles, defaults to the data's
+
+	// Arrival processes
+	Rate        float64   `json:"rate,omitempty"`         // Arrivals per hour for poisson and nhpp
+	RateProfile []float64 `json:"rate_profile,omitempty"` // 24 hourly or 168 weekly rate multipliers for nhpp
+	MMPP        string    `json:"mmpp,omitempty"`         // rate:dwell states for mmpp, e.g. 20:2h,200:15m
+
+	// Weighted components of the mixture workload, e.g. weekdaySpike:0.6,nightSpike:0.4
+	Mixture string `json:"mixture,omitempty"`
+
+	// Arrival log replayed by the trace workload
+	Arrivals       string `json:"arrivals,omitempty"`
+	ArrivalsFormat string `json:"arrivals_format,omitempty"` // csv or jsonl, inferred from the extension when empty
+	Align          string `json:"align,omitempty"`           // none, start or week
+}
+
+type PolicyConfig struct {
+	Name     string  `json:"name"`
+	Model    string  `json:"model,omitempty"`    // Used by fifo, temporal and interruptible, restricts the oracle
+	Accuracy float64 `json:"accuracy,omitempty"` // Used by modelSelection, hybridSelection and oracle
+	// How modelSelection and hybridSelection enforce the accuracy: global,
+	// window:N jobs, window:T duration, tenant or job. Defaults to global
+	AccuracyConstraint string  `json:"accuracy_constraint,omitempty"`
+	SafeguardSD        float64 `json:"safeguard_sd"`
+	// Time a resumed job spends restoring its checkpoint, used by interruptible
+	CheckpointOverhead string `json:"checkpoint_overhead,omitempty"`
+}
+
+// ForecastConfig describes the forecast policies plan with. Every field left
+// empty gives a perfect forecast.
+type ForecastConfig struct {
+	Error       float64 `json:"error,omitempty"`           // Relative standard deviation of the error at zero lead time
+	Growth      float64 `json:"growth_per_hour,omitempty"` // Added relative standard deviation per hour of lead time
+	Correlation float64 `json:"correlation,omitempty"`     // Correlation between errors of consecutive intervals, 0-1
+	Lag         string  `json:"lag,omitempty"`             // Duration the forecast trails the data by, e.g. 24h
+	Horizon     string  `json:"horizon,omitempty"`         // How far ahead forecasts reach, e.g. 48h, empty for unlimited
+	File        string  `json:"file,omitempty"`            // Forecast intensities in the carbon data format
+}
+
+// DataSchemaConfig describes where carbon data files keep their times and
+// intensities, see loader.Schema. Fields left empty follow the provider.
+type DataSchemaConfig struct {
+	Provider   string `json:"provider,omitempty"`         // singularity, electricitymaps, watttime or ukgrid
+	Format     string `json:"format,omitempty"`           // csv or json, inferred from the extension when empty
+	Records    string `json:"records,omitempty"`          // Dotted path to the array of records in JSON files
+	Time       string `json:"time_column,omitempty"`      // Column or dotted JSON field holding each interval's start
+	TimeLayout string `json:"time_layout,omitempty"`      // Go time layout, RFC 3339 or Unix seconds when empty
+	Intensity  string `json:"intensity_column,omitempty"` // Column or dotted JSON field holding the intensity
+	Marginal   string `json:"marginal_column,omitempty"`  // Column or dotted JSON field holding the marginal emission rate
+	Unit       string `json:"unit,omitempty"`             // kg/MWh, g/kWh or lbs/MWh
+}
+
+// DataQualityConfig decides how gaps in the carbon data are treated. By
+// default the previous entry covers every gap, as it always has.
+type DataQualityConfig struct {
+	Resample      string `json:"resample,omitempty"`      // Interval to resample the data to, e.g. 5m, empty to keep the data's own
+	Interpolation string `json:"interpolation,omitempty"` // previous, linear or cubic, previous when empty
+	Strict        bool   `json:"strict,omitempty"`        // Refuse data with gaps longer than MaxGap
+	MaxGap        string `json:"max_gap,omitempty"`       // Longest gap strict mode accepts, e.g. 1h, no gap at all when empty
+}
+
+// SignalConfig chooses the emission factor, average or marginal, policies
+// optimise against and the one emissions are reported in. Both default to
+// average.
+type SignalConfig struct {
+	Decision   string `json:"decision,omitempty"`   // Signal policies and forecasts see
+	Accounting string `json:"accounting,omitempty"` // Signal total carbon is reported in
+}
+
+type ClusterConfig struct {
+	Capacity int            `json:"capacity"` // 0 for unlimited
+	Slots    map[string]int `json:"slots,omitempty"`
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeConfig writes the JSON to a file in dir and returns its path.
+func writeConfig(t *testing.T, dir, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, "experiment.json")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	return path
+}
+
+func TestLoadStrict(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  bool
+		check    func(t *testing.T, exp *Experiment)
+	}{
+		{
+			name:     "defaults kept",
+			contents: `{"region": "CAISO", "slo": "6hr", "policy": {"name": "fifo"}}`,
+			check: func(t *testing.T, exp *Experiment) {
+				if exp.Workload.Name != "random" || exp.Workload.Jobs != 1000000 || exp.ModelsFile != filepath.Join("cmd", "AIModels.json") {
+					t.Errorf("defaults not kept: workload %s of %d jobs, models %s", exp.Workload.Name, exp.Workload.Jobs, exp.ModelsFile)
+				}
+			},
+		},
+		{
+			name:     "nested defaults kept",
+			contents: `{"slo": "6hr", "workload": {"name": "poisson", "rate": 20}}`,
+			check: func(t *testing.T, exp *Experiment) {
+				if exp.Workload.Name != "poisson" || exp.Workload.Rate != 20 || exp.Workload.Jobs != 1000000 || exp.Workload.SpikeFraction != 0.8 {
+					t.Errorf("workload is %+v, want poisson at 20 per hour with the default jobs and spike fraction", exp.Workload)
+				}
+			},
+		},
+		{name: "unknown field", contents: `{"slo": "6hr", "polcy": {"name": "fifo"}}`, wantErr: true},
+		{name: "unknown nested field", contents: `{"slo": "6hr", "workload": {"job": 10}}`, wantErr: true},
+		{name: "wrong type", contents: `{"slo": "6hr", "workload": {"jobs": "ten"}}`, wantErr: true},
+		{name: "malformed", contents: `{"slo": "6hr",`, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exp, err := Load(writeConfig(t, t.TempDir(), test.contents))
+			if test.wantErr {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Errorf("Load error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			test.check(t, exp)
+		})
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("loading a missing config succeeded, want an error")
+	}
+}
+
+func TestLoadRelativePaths(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "configs")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("creating config directory: %v", err)
+	}
+	absolute := filepath.Join(t.TempDir(), "models.json")
+	path := writeConfig(t, dir, `{
+		"data_dir": "collected",
+		"data_file": "carbon/CAISO.csv",
+		"models_file": "`+filepath.ToSlash(absolute)+`",
+		"slo": "6hr",
+		"workload": {"name": "trace", "arrivals": "../arrivals.csv"},
+		"forecast": {"file": "./forecast.csv"}
+	}`)
+	exp, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "data directory", got: exp.DataDir, want: filepath.Join(dir, "collected")},
+		{name: "data file", got: exp.DataFile, want: filepath.Join(dir, "carbon", "CAISO.csv")},
+		{name: "absolute models file", got: exp.ModelsFile, want: absolute},
+		{name: "arrivals above the config", got: exp.Workload.Arrivals, want: filepath.Join(filepath.Dir(dir), "arrivals.csv")},
+		{name: "forecast file", got: exp.Forecast.File, want: filepath.Join(dir, "forecast.csv")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.got != test.want {
+				t.Errorf("resolved to %s, want %s", test.got, test.want)
+			}
+		})
+	}
+
+	// Defaults are relative to the working directory, not the config
+	exp, err = Load(writeConfig(t, dir, `{"region": "CAISO", "slo": "6hr"}`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if exp.DataDir != filepath.Join("data", "collected") {
+		t.Errorf("default data directory resolved to %s, want it left as data/collected", exp.DataDir)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name      string
+		exp       Experiment
+		wantNames []string
+		check     func(t *testing.T, expanded []Experiment)
+		wantErr   bool
+	}{
+		{
+			name:      "no grid",
+			exp:       Experiment{Name: "plain", SLO: "6hr"},
+			wantNames: []string{"plain"},
+		},
+		{
+			name: "product in key order",
+			exp: Experiment{Name: "sweep", SLO: "6hr", Grid: map[string][]any{
+				"slo":             {"6hr", "12hr"},
+				"policy.accuracy": {0.7, 0.8},
+			}},
+			wantNames: []string{
+				"sweep [policy.accuracy=0.7, slo=6hr]",
+				"sweep [policy.accuracy=0.7, slo=12hr]",
+				"sweep [policy.accuracy=0.8, slo=6hr]",
+				"sweep [policy.accuracy=0.8, slo=12hr]",
+			},
+			check: func(t *testing.T, expanded []Experiment) {
+				if expanded[1].Policy.Accuracy != 0.7 || expanded[1].SLO != "12hr" {
+					t.Errorf("second experiment has accuracy %f and SLO %s, want 0.7 and 12hr", expanded[1].Policy.Accuracy, expanded[1].SLO)
+				}
+				for _, exp := range expanded {
+					if exp.Grid != nil {
+						t.Errorf("expanded experiment %s still has a grid", exp.Name)
+					}
+				}
+			},
+		},
+		{
+			name: "nested field left unset",
+			exp: Experiment{SLO: "6hr", Grid: map[string][]any{
+				"cluster.capacity": {2, 4},
+			}},
+			wantNames: []string{"[cluster.capacity=2]", "[cluster.capacity=4]"},
+			check: func(t *testing.T, expanded []Experiment) {
+				if expanded[0].Cluster.Capacity != 2 || expanded[1].Cluster.Capacity != 4 {
+					t.Errorf("capacities are %d and %d, want 2 and 4", expanded[0].Cluster.Capacity, expanded[1].Cluster.Capacity)
+				}
+			},
+		},
+		{
+			name: "oracle baselines",
+			exp: Experiment{SLO: "6hr", Baseline: true, Policy: PolicyConfig{Name: "temporal", Model: "small"}, Grid: map[string][]any{
+				"slo": {"6hr", "12hr"},
+			}},
+			wantNames: []string{"[slo=6hr]", "[slo=6hr] [oracle baseline]", "[slo=12hr]", "[slo=12hr] [oracle baseline]"},
+			check: func(t *testing.T, expanded []Experiment) {
+				for i := 0; i < len(expanded); i += 2 {
+					run, baseline := expanded[i], expanded[i+1]
+					if run.Seed == 0 || baseline.Seed != run.Seed {
+						t.Errorf("run and baseline have seeds %d and %d, want the same picked seed", run.Seed, baseline.Seed)
+					}
+					if baseline.Policy.Name != "oracle" || baseline.Policy.Model != "small" || baseline.Baseline {
+						t.Errorf("baseline policy is %+v, want the oracle restricted to small", baseline.Policy)
+					}
+				}
+			},
+		},
+		{name: "empty values", exp: Experiment{Grid: map[string][]any{"slo": {}}}, wantErr: true},
+		{name: "unknown field", exp: Experiment{Grid: map[string][]any{"policy.acuracy": {0.7}}}, wantErr: true},
+		{name: "wrong type", exp: Experiment{Grid: map[string][]any{"workload.jobs": {"many"}}}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expanded, err := test.exp.Expand()
+			if test.wantErr {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Errorf("Expand error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expand: %v", err)
+			}
+			if len(expanded) != len(test.wantNames) {
+				t.Fatalf("expanded to %d experiments, want %d", len(expanded), len(test.wantNames))
+			}
+			for i, exp := range expanded {
+				if exp.Name != test.wantNames[i] {
+					t.Errorf("experiment %d is named %q, want %q", i, exp.Name, test.wantNames[i])
+				}
+			}
+			if test.check != nil {
+				test.check(t, expanded)
+			}
+		})
+	}
+}
@@ -6,42 +6,21 @@ import (
 	"io"
 	"log"
 	"os"
-	"sync"
+	"slices"
 )
 
-var lock = &sync.Mutex{}
-var singleton *Directory
-
 func NewDirectory(filename string) *Directory {
-	if singleton == nil {
-		lock.Lock()
-		defer lock.Unlock()
-		if singleton == nil {
-			log.Println("Initializing Directory with filename:", filename)
-			singleton = &Directory{
-				filename: filename,
-				models:   make(map[string]AIModelDefinition),
-			}
-			err := singleton.loadFromFile()
-			if err != nil {
-				log.Printf("Error loading from file: %v", err)
-				return nil
-			}
-		} else {
-			log.Println("Directory already initialized")
-		}
-	} else {
-		log.Println("Directory already initialized")
+	log.Println("Initializing Directory with filename:", filename)
+	modelDirectory := &Directory{
+		filename: filename,
+		models:   make(map[string]AIModelDefinition),
 	}
-	return singleton
-}
-
-func FetchDirectory() *Directory {
-	if singleton == nil {
-		log.Println("Directory not initialized. Call NewDirectory first.")
+	err := modelDirectory.loadFromFile()
+	if err != nil {
+		log.Printf("Error loading from file: %v", err)
 		return nil
 	}
-	return singleton
+	return modelDirectory
 }
 
 func (d *Directory) loadFromFile() error {
@@ -79,3 +58,14 @@ func (d *Directory) GetModelDefinition(modelName string) (*AIModelDefinition, er
 func (d *Directory) GetModels() map[string]AIModelDefinition {
 	return d.models
 }
+
+// GetModelNames returns the model names in sorted order. Iterating models in
+// this order keeps policy decisions reproducible across runs.
+func (d *Directory) GetModelNames() []string {
+	names := make([]string, 0, len(d.models))
+	for name := range d.models {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
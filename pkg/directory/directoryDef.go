@@ -7,6 +7,7 @@ type DirectoryInterface interface {
 	String() string
 	GetModelDefinition(modelName string) (*AIModelDefinition, error)
 	GetModels() map[string]AIModelDefinition
+	GetModelNames() []string
 }
 
 type Directory struct {
@@ -21,4 +22,5 @@ type AIModelDefinition struct {
 	EnergyUsage   float64 `json:"energy_usage"`     // in MW
 	SLOThreshold  float64 `json:"slo_threshold"`    // in seconds
 	Accuracy      float64 `json:"accuracy"`         // in percentage
+	Slots         int     `json:"slots"`            // accelerators occupied while running, defaults to 1
 }
@@ -0,0 +1,80 @@
+package environment
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"simulator/pkg/directory"
+	"simulator/pkg/forecast"
+	"simulator/pkg/loader"
+)
+
+// NewEnvironment creates an environment whose random draws are all taken from
+// a generator seeded with seed, so identical seeds reproduce identical runs.
+func NewEnvironment(dataLoader *loader.Loader, modelDirectory *directory.Directory, seed int64) (*Environment, error) {
+	if dataLoader == nil {
+		return nil, fmt.Errorf("loader not initialized")
+	}
+	if dataLoader.NumEntries() == 0 {
+		return nil, fmt.Errorf("loader has no data")
+	}
+	if modelDirectory == nil {
+		return nil, fmt.Errorf("model directory not initialized")
+	}
+	return &Environment{
+		Loader:    dataLoader,
+		Directory: modelDirectory,
+		Logger:    log.New(os.Stderr, "", log.LstdFlags),
+		Rand:      rand.New(rand.NewSource(seed)),
+		Seed:      seed,
+	}, nil
+}
+
+func (e *Environment) String() string {
+	return fmt.Sprintf("Environment with %s, %s and seed %d", e.Loader, e.Directory, e.Seed)
+}
+
+// Region returns the named region, or nil when it is not simulated.
+func (e *Environment) Region(name string) *Region {
+	for i := range e.Regions {
+		if e.Regions[i].Name == name {
+			return &e.Regions[i]
+		}
+	}
+	return nil
+}
+
+// LoaderFor returns the carbon data of the named region, falling back to the
+// home Loader for jobs that were not routed.
+func (e *Environment) LoaderFor(region string) *loader.Loader {
+	if r := e.Region(region); r != nil {
+		return r.Loader
+	}
+	return e.Loader
+}
+
+// AccountingFor returns the carbon data emissions in the named region are
+// reported in, which differs from LoaderFor when accounting uses another
+// signal than the policies.
+func (e *Environment) AccountingFor(region string) *loader.Loader {
+	if r := e.Region(region); r != nil {
+		if r.Accounting != nil {
+			return r.Accounting
+		}
+		return r.Loader
+	}
+	if e.Accounting != nil {
+		return e.Accounting
+	}
+	return e.Loader
+}
+
+// ForecastFor returns the forecast policies plan with in the named region,
+// falling back to the home Forecast for jobs that were not routed.
+func (e *Environment) ForecastFor(region string) *forecast.Forecast {
+	if r := e.Region(region); r != nil {
+		return r.Forecast
+	}
+	return e.Forecast
+}
@@ -0,0 +1,41 @@
+package environment
+
+import (
+	"log"
+	"math/rand"
+	"simulator/pkg/directory"
+	"simulator/pkg/forecast"
+	"simulator/pkg/loader"
+	"time"
+)
+
+type EnvironmentInterface interface {
+	// Public Methods
+	String() string
+}
+
+// Environment bundles everything a single simulation reads from. Separate
+// environments share nothing mutable, so independent simulations can run
+// side by side in one process.
+type Environment struct {
+	Loader     *loader.Loader       // Carbon intensity data for the region, in the signal policies optimise
+	Accounting *loader.Loader       // Carbon intensity emissions are reported in, nil when it is Loader
+	Forecast   *forecast.Forecast   // Intensity policies plan with, nil for perfect knowledge of Loader
+	Regions    []Region             // Regions jobs may be routed to, empty when only Loader is simulated
+	Directory  *directory.Directory // AI model definitions
+	Logger     *log.Logger          // Destination for simulation events
+	Rand       *rand.Rand           // Source of every random draw in the simulation
+	Seed       int64                // Seed Rand was created with
+}
+
+// Region is a location jobs can be routed to in multi-region runs. A job's
+// Region field names the region it runs in, empty for the home Loader.
+type Region struct {
+	Name           string
+	Loader         *loader.Loader     // Carbon intensity measured in the region, in the signal policies optimise
+	Accounting     *loader.Loader     // Carbon intensity emissions are reported in, nil when it is Loader
+	Forecast       *forecast.Forecast // Intensity policies plan with, nil for perfect knowledge of Loader
+	Capacity       int                // Accelerators in the region, 0 for unlimited
+	Latency        time.Duration      // Delay before a job routed to the region can start
+	TransferCarbon float64            // gCO2 charged for moving a job's data to the region
+}
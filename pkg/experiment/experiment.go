@@ -0,0 +1,238 @@
+package experiment
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"runtime"
+	"simulator/pkg/environment"
+	"simulator/pkg/forecast"
+	"simulator/pkg/simulator"
+	"simulator/pkg/workload"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Replicate runs the configuration replications times, at most parallel at a
+// time, and summarises the outcomes. A parallelism of zero uses every CPU.
+func Replicate(cfg Config, replications int, parallel int) (*Report, error) {
+	if replications <= 0 {
+		return nil, fmt.Errorf("number of replications must be positive, got %d", replications)
+	}
+	if cfg.NewPolicy == nil {
+		return nil, fmt.Errorf("no policy factory configured")
+	}
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+
+	results := make([]*simulator.Results, replications)
+	errs := make([]error, replications)
+	semaphore := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	wg.Add(replications)
+	for i := range replications {
+		go func(index int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			replication, err := RunOnce(cfg, cfg.Seed+int64(index))
+			if err != nil {
+				errs[index] = fmt.Errorf("replication %d: %w", index, err)
+				return
+			}
+			results[index] = replication
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return summarizeReplications(results), nil
+}
+
+// RunOnce runs a single simulation of the configuration with the given seed.
+// Event logs are discarded since replications are only compared in aggregate.
+func RunOnce(cfg Config, seed int64) (*simulator.Results, error) {
+	_, results, err := Run(cfg, seed, RunOptions{EventLog: io.Discard})
+	return results, err
+}
+
+// Run runs a single simulation of the configuration with the given seed and
+// returns the finished simulator alongside its results.
+func Run(cfg Config, seed int64, opts RunOptions) (*simulator.Simulator, *simulator.Results, error) {
+	env, err := environment.NewEnvironment(cfg.Loader, cfg.Directory, seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Logger != nil {
+		env.Logger = opts.Logger
+	} else {
+		env.Logger = log.New(io.Discard, "", 0)
+	}
+	if !cfg.Forecast.Perfect() {
+		env.Forecast = forecast.New(cfg.Loader, cfg.Forecast, seed)
+	}
+	env.Accounting = cfg.Accounting
+	env.Regions = slices.Clone(cfg.Regions)
+	for i := range env.Regions {
+		if env.Regions[i].Loader == cfg.Loader {
+			env.Regions[i].Forecast = env.Forecast
+		} else if !cfg.Forecast.Perfect() {
+			// Every region's forecast errors are drawn independently
+			env.Regions[i].Forecast = forecast.New(env.Regions[i].Loader, cfg.Forecast, seed+int64(i)+1)
+		}
+	}
+	generated, err := workload.GetWorkload(env, cfg.JobInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	policy, err := cfg.NewPolicy(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	simElement := simulator.NewSimulator(env, generated.Jobs, policy, simulator.NewCluster(cfg.Capacity, cfg.Slots))
+	if simElement == nil {
+		return nil, nil, fmt.Errorf("simulator not initialized")
+	}
+	if opts.EventLog != nil {
+		simElement.SetEventLog(opts.EventLog)
+	}
+	if opts.Trace != nil {
+		simElement.SetTrace(opts.Trace)
+	}
+	if cfg.AccuracyTarget > 0 {
+		if err := simElement.SetAccuracyConstraints(cfg.AccuracyTarget, cfg.AccuracyConstraints); err != nil {
+			return nil, nil, err
+		}
+	}
+	results, err := simElement.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	return simElement, results, nil
+}
+
+func summarizeReplications(results []*simulator.Results) *Report {
+	modelNames := make([]string, 0)
+	for _, result := range results {
+		for _, name := range result.ModelNames() {
+			if !slices.Contains(modelNames, name) {
+				modelNames = append(modelNames, name)
+			}
+		}
+	}
+	// A model missing from a replication contributed zero to it
+	carbon := make([]float64, len(results))
+	violations := make([]float64, len(results))
+	accuracy := make([]float64, len(results))
+	for i, result := range results {
+		carbon[i] = result.TotalCarbon
+		violations[i] = float64(result.SLOTimeouts)
+		accuracy[i] = result.MeanAccuracy
+	}
+	report := &Report{
+		Replications:         results,
+		TotalCarbon:          Summarize(carbon),
+		CarbonByModel:        make(map[string]Summary, len(modelNames)),
+		SLOViolations:        Summarize(violations),
+		SLOViolationsByModel: make(map[string]Summary, len(modelNames)),
+		MeanAccuracy:         Summarize(accuracy),
+	}
+	for _, name := range modelNames {
+		modelCarbon := make([]float64, len(results))
+		modelViolations := make([]float64, len(results))
+		for i, result := range results {
+			modelCarbon[i] = result.CarbonByModel[name]
+			modelViolations[i] = float64(result.SLOTimeoutsByModel[name])
+		}
+		report.CarbonByModel[name] = Summarize(modelCarbon)
+		report.SLOViolationsByModel[name] = Summarize(modelViolations)
+	}
+	classNames := make([]string, 0)
+	for _, result := range results {
+		for name := range result.JobsByClass {
+			if !slices.Contains(classNames, name) {
+				classNames = append(classNames, name)
+			}
+		}
+	}
+	if len(classNames) > 0 {
+		report.SLOViolationsByClass = make(map[string]Summary, len(classNames))
+	}
+	for _, name := range classNames {
+		classViolations := make([]float64, len(results))
+		for i, result := range results {
+			classViolations[i] = float64(result.SLOTimeoutsByClass[name])
+		}
+		report.SLOViolationsByClass[name] = Summarize(classViolations)
+	}
+	// Replications that never accounted in another signal have no decision carbon
+	decisionCarbon := make([]float64, 0, len(results))
+	for _, result := range results {
+		if result.DecisionCarbon != nil {
+			decisionCarbon = append(decisionCarbon, *result.DecisionCarbon)
+		}
+	}
+	if len(decisionCarbon) > 0 {
+		summary := Summarize(decisionCarbon)
+		report.DecisionCarbon = &summary
+	}
+	for _, result := range results {
+		for name := range result.AccuracyViolations {
+			if report.AccuracyViolations == nil {
+				report.AccuracyViolations = make(map[string]Summary)
+			}
+			if _, ok := report.AccuracyViolations[name]; ok {
+				continue
+			}
+			constraintViolations := make([]float64, len(results))
+			for i, result := range results {
+				constraintViolations[i] = float64(result.AccuracyViolations[name])
+			}
+			report.AccuracyViolations[name] = Summarize(constraintViolations)
+		}
+	}
+	return report
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("mean %f, sd %f, 95%% CI [%f, %f] (n=%d)", s.Mean, s.StdDev, s.CILow, s.CIHigh, s.N)
+}
+
+func (r *Report) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "\nReplication Report:\n")
+	fmt.Fprintf(&builder, "\tReplications: %d\n", len(r.Replications))
+	fmt.Fprintf(&builder, "\tTotal Carbon (gCO2): %s\n", r.TotalCarbon)
+	modelNames := make([]string, 0, len(r.CarbonByModel))
+	for name := range r.CarbonByModel {
+		modelNames = append(modelNames, name)
+	}
+	slices.Sort(modelNames)
+	for _, name := range modelNames {
+		fmt.Fprintf(&builder, "\t\t%s: %s\n", name, r.CarbonByModel[name])
+	}
+	if r.DecisionCarbon != nil {
+		fmt.Fprintf(&builder, "\tDecision Carbon (gCO2): %s\n", r.DecisionCarbon)
+	}
+	fmt.Fprintf(&builder, "\tSLO Violations: %s\n", r.SLOViolations)
+	for _, name := range modelNames {
+		fmt.Fprintf(&builder, "\t\t%s: %s\n", name, r.SLOViolationsByModel[name])
+	}
+	for _, name := range slices.Sorted(maps.Keys(r.SLOViolationsByClass)) {
+		fmt.Fprintf(&builder, "\t\t%s class: %s\n", name, r.SLOViolationsByClass[name])
+	}
+	fmt.Fprintf(&builder, "\tMean Accuracy: %s\n", r.MeanAccuracy)
+	if len(r.AccuracyViolations) > 0 {
+		fmt.Fprintf(&builder, "\tAccuracy Violations:\n")
+	}
+	for _, name := range slices.Sorted(maps.Keys(r.AccuracyViolations)) {
+		fmt.Fprintf(&builder, "\t\t%s: %s\n", name, r.AccuracyViolations[name])
+	}
+	return builder.String()
+}
@@ -0,0 +1,65 @@
+package experiment
+
+import (
+	"io"
+	"log"
+	"simulator/pkg/directory"
+	"simulator/pkg/environment"
+	"simulator/pkg/forecast"
+	"simulator/pkg/loader"
+	"simulator/pkg/simulator"
+	"simulator/pkg/workload"
+)
+
+// PolicyFactory builds a fresh scheduling policy for one replication. Policies
+// carry state, so every replication needs its own instance.
+type PolicyFactory func(env *environment.Environment) (simulator.PolicyInterface, error)
+
+// Config describes one experiment configuration to be replicated.
+type Config struct {
+	Loader     *loader.Loader       // Shared, read-only carbon data in the signal policies optimise
+	Accounting *loader.Loader       // Shared, read-only carbon data emissions are reported in, nil for Loader
+	Directory  *directory.Directory // Shared, read-only model definitions
+	JobInfo    workload.JobMetadata
+	Capacity   int                  // Cluster capacity, 0 for unlimited
+	Slots      map[string]int       // Per-model slot overrides
+	Forecast   forecast.Model       // How the intensity policies see departs from the data
+	Regions    []environment.Region // Regions jobs may be routed to, empty for single-region runs
+
+	// Accuracy constraints whose violations are reported, checked against
+	// AccuracyTarget, see policies.ParseAccuracyConstraint
+	AccuracyTarget      float64
+	AccuracyConstraints []string
+
+	NewPolicy PolicyFactory
+	Seed      int64 // Replication i runs with seed Seed+i
+}
+
+// RunOptions customise how a single run reports what it does.
+type RunOptions struct {
+	EventLog io.Writer             // Event log destination, nil for a timestamped log file
+	Trace    simulator.TraceWriter // Per-job trace, nil to disable
+	Logger   *log.Logger           // Setup and event logger, nil discards setup messages
+}
+
+// Summary describes a sample of replicated measurements.
+type Summary struct {
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"` // Sample standard deviation
+	CILow  float64 `json:"ci_low"`  // Lower bound of the 95% confidence interval of the mean
+	CIHigh float64 `json:"ci_high"` // Upper bound of the 95% confidence interval of the mean
+}
+
+// Report aggregates every replication of a configuration.
+type Report struct {
+	Replications         []*simulator.Results `json:"replications"`
+	TotalCarbon          Summary              `json:"total_carbon_g"`
+	CarbonByModel        map[string]Summary   `json:"carbon_by_model_g"`
+	DecisionCarbon       *Summary             `json:"decision_carbon_g,omitempty"` // Only when accounting uses another signal
+	SLOViolations        Summary              `json:"slo_timeouts"`
+	SLOViolationsByModel map[string]Summary   `json:"slo_timeouts_by_model"`
+	SLOViolationsByClass map[string]Summary   `json:"slo_timeouts_by_class,omitempty"`
+	AccuracyViolations   map[string]Summary   `json:"accuracy_violations,omitempty"`
+	MeanAccuracy         Summary              `json:"mean_accuracy"`
+}
@@ -0,0 +1,43 @@
+package experiment
+
+import (
+	"simulator/pkg/simulator"
+	"testing"
+)
+
+func TestSummarizeReplicationsDecisionCarbon(t *testing.T) {
+	carbon := func(value float64) *float64 { return &value }
+	tests := []struct {
+		name   string
+		values []*float64 // Decision carbon of each replication
+		wantN  int        // Replications summarised, 0 for no summary
+		want   float64    // Mean decision carbon
+	}{
+		{name: "no replications"},
+		{name: "same signal throughout", values: []*float64{nil, nil}},
+		{name: "every replication", values: []*float64{carbon(10), carbon(20)}, wantN: 2, want: 15},
+		{name: "first replication without", values: []*float64{nil, carbon(10), carbon(30)}, wantN: 2, want: 20},
+		{name: "last replication without", values: []*float64{carbon(10), nil}, wantN: 1, want: 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results := make([]*simulator.Results, len(test.values))
+			for i, value := range test.values {
+				results[i] = &simulator.Results{DecisionCarbon: value}
+			}
+			report := summarizeReplications(results)
+			if test.wantN == 0 {
+				if report.DecisionCarbon != nil {
+					t.Errorf("decision carbon summarised as %v, want none", report.DecisionCarbon)
+				}
+				return
+			}
+			if report.DecisionCarbon == nil {
+				t.Fatalf("decision carbon not summarised")
+			}
+			if report.DecisionCarbon.N != test.wantN || report.DecisionCarbon.Mean != test.want {
+				t.Errorf("decision carbon summary %+v, want %d replications with mean %f", *report.DecisionCarbon, test.wantN, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package experiment
+
+import "math"
+
+// tCritical975 holds the 0.975 quantile of Student's t distribution for 1 to 30
+// degrees of freedom.
+var tCritical975 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tQuantile975 returns the 0.975 quantile of Student's t distribution. Beyond
+// the table it uses the Cornish-Fisher expansion around the normal quantile,
+// accurate to four decimals from 30 degrees of freedom.
+func tQuantile975(degreesOfFreedom int) float64 {
+	if degreesOfFreedom <= 0 {
+		return math.NaN()
+	}
+	if degreesOfFreedom <= len(tCritical975) {
+		return tCritical975[degreesOfFreedom-1]
+	}
+	const z = 1.959963984540054
+	v := float64(degreesOfFreedom)
+	z3, z5, z7 := z*z*z, math.Pow(z, 5), math.Pow(z, 7)
+	return z +
+		(z3+z)/(4*v) +
+		(5*z5+16*z3+3*z)/(96*v*v) +
+		(3*z7+19*z5+17*z3-15*z)/(384*v*v*v)
+}
+
+// Summarize computes the mean, sample standard deviation and 95% confidence
+// interval of the mean. With fewer than two values the spread is zero and the
+// interval collapses onto the mean.
+func Summarize(values []float64) Summary {
+	n := len(values)
+	if n == 0 {
+		return Summary{}
+	}
+	mean := 0.0
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(n)
+	if n == 1 {
+		return Summary{N: n, Mean: mean, CILow: mean, CIHigh: mean}
+	}
+	squares := 0.0
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+	stdDev := math.Sqrt(squares / float64(n-1))
+	halfWidth := tQuantile975(n-1) * stdDev / math.Sqrt(float64(n))
+	return Summary{
+		N:      n,
+		Mean:   mean,
+		StdDev: stdDev,
+		CILow:  mean - halfWidth,
+		CIHigh: mean + halfWidth,
+	}
+}
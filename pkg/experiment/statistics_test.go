@@ -0,0 +1,85 @@
+package experiment
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTQuantile975(t *testing.T) {
+	tests := []struct {
+		degreesOfFreedom int
+		want             float64
+	}{
+		{1, 12.7062},
+		{2, 4.3027},
+		{5, 2.5706},
+		{10, 2.2281},
+		{30, 2.0423},
+		{31, 2.0395},
+		{40, 2.0211},
+		{60, 2.0003},
+		{100, 1.9840},
+		{1000, 1.9623},
+	}
+	for _, test := range tests {
+		if got := tQuantile975(test.degreesOfFreedom); math.Abs(got-test.want) > 5e-4 {
+			t.Errorf("tQuantile975(%d) = %f, want %f", test.degreesOfFreedom, got, test.want)
+		}
+	}
+	// Quantiles shrink towards the normal one as the degrees of freedom grow
+	for df := 2; df <= 200; df++ {
+		if tQuantile975(df) >= tQuantile975(df-1) {
+			t.Errorf("tQuantile975(%d) = %f, not below tQuantile975(%d) = %f", df, tQuantile975(df), df-1, tQuantile975(df-1))
+		}
+	}
+	for _, df := range []int{0, -1} {
+		if got := tQuantile975(df); !math.IsNaN(got) {
+			t.Errorf("tQuantile975(%d) = %f, want NaN", df, got)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   Summary
+	}{
+		{name: "no values", want: Summary{}},
+		{name: "one value", values: []float64{4}, want: Summary{N: 1, Mean: 4, CILow: 4, CIHigh: 4}},
+		{
+			// The half width is t(1) * sqrt(2) / sqrt(2)
+			name:   "two values",
+			values: []float64{1, 3},
+			want:   Summary{N: 2, Mean: 2, StdDev: math.Sqrt2, CILow: 2 - 12.706, CIHigh: 2 + 12.706},
+		},
+		{name: "identical values", values: []float64{5, 5, 5}, want: Summary{N: 3, Mean: 5, CILow: 5, CIHigh: 5}},
+		{
+			// The half width is t(3) * s / sqrt(4) with s the sample standard deviation
+			name:   "four values",
+			values: []float64{2, 4, 4, 6},
+			want:   Summary{N: 4, Mean: 4, StdDev: math.Sqrt(8.0 / 3), CILow: 4 - 3.182*math.Sqrt(8.0/3)/2, CIHigh: 4 + 3.182*math.Sqrt(8.0/3)/2},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Summarize(test.values)
+			if got.N != test.want.N {
+				t.Errorf("N = %d, want %d", got.N, test.want.N)
+			}
+			for _, field := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"mean", got.Mean, test.want.Mean},
+				{"standard deviation", got.StdDev, test.want.StdDev},
+				{"interval low", got.CILow, test.want.CILow},
+				{"interval high", got.CIHigh, test.want.CIHigh},
+			} {
+				if math.Abs(field.got-field.want) > 1e-9 {
+					t.Errorf("%s = %f, want %f", field.name, field.got, field.want)
+				}
+			}
+		})
+	}
+}
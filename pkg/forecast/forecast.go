@@ -0,0 +1,137 @@
+package forecast
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"simulator/pkg/loader"
+	"time"
+)
+
+// Perfect reports whether forecasts under the model equal the measured data.
+func (m Model) Perfect() bool {
+	return m.Error == 0 && m.Growth == 0 && m.Lag == 0 && m.Horizon == 0 && m.Source == nil
+}
+
+func (m Model) String() string {
+	if m.Perfect() {
+		return "perfect forecast"
+	}
+	description := fmt.Sprintf("forecast error %f growing %f per hour with correlation %f", m.Error, m.Growth, m.Correlation)
+	if m.Lag > 0 {
+		description += fmt.Sprintf(", lagged %v", m.Lag)
+	}
+	if m.Horizon > 0 {
+		description += fmt.Sprintf(", reaching %v ahead", m.Horizon)
+	}
+	if m.Source != nil {
+		description += fmt.Sprintf(", from %s", m.Source)
+	}
+	return description
+}
+
+// New draws the forecast errors for every interval of truth. The errors come
+// from their own generator seeded with seed, so a run with forecast errors
+// sees the same workload and runtimes as one without.
+func New(truth *loader.Loader, model Model, seed int64) *Forecast {
+	r := rand.New(rand.NewSource(seed))
+	noise := make([]float64, truth.NumEntries())
+	innovation := math.Sqrt(1 - model.Correlation*model.Correlation)
+	for i := range noise {
+		if i == 0 {
+			noise[i] = r.NormFloat64()
+			continue
+		}
+		noise[i] = model.Correlation*noise[i-1] + innovation*r.NormFloat64()
+	}
+	return &Forecast{
+		truth: truth,
+		model: model,
+		noise: noise,
+	}
+}
+
+// Horizon returns how far ahead forecasts reach, 0 when they are unlimited.
+func (f *Forecast) Horizon() time.Duration {
+	if f == nil {
+		return 0
+	}
+	return f.model.Horizon
+}
+
+// View returns the intensity of the forecast in force at issued, the one
+// issued at the start of its interval, for the intervals from issued up to and
+// including the one covering until, or the end of the horizon if it comes
+// first. Errors grow with the lead time of each interval, so later views of
+// the same interval are more accurate. Views of one issue share its data.
+func (f *Forecast) View(issued, until time.Time) *loader.Loader {
+	if f.model.Horizon > 0 && until.After(issued.Add(f.model.Horizon)) {
+		until = issued.Add(f.model.Horizon)
+	}
+	first, err := f.truth.GetIndexByDate(issued)
+	if err != nil {
+		first = 0
+		if issued.After(f.truth.StartDate()) {
+			first = f.truth.NumEntries() - 1
+		}
+	}
+	last, err := f.truth.GetIndexByDate(until)
+	if err != nil || last < first {
+		last = f.truth.NumEntries() - 1
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.issued == nil || f.issue != first {
+		f.issue = first
+		f.issued = loader.NewLoaderFromData(fmt.Sprintf("forecast issued %s", f.truth.Data[first].StartDate.Format(time.RFC3339)), nil, f.truth.Location())
+		f.issued.SetInterval(f.truth.Interval())
+	}
+	// Extend the issue out to until, the intervals before it stay as they were
+	if reached := first + f.issued.NumEntries(); reached <= last {
+		points := make([]loader.DataPoint, last-reached+1)
+		data := make([]*loader.DataPoint, len(points))
+		for i := range points {
+			points[i] = f.forecast(first, reached+i)
+			data[i] = &points[i]
+		}
+		// The points follow the issue by construction, which is all Append checks
+		_ = f.issued.Append(data...)
+	}
+	return f.issued.Slice(0, last-first+1)
+}
+
+// forecast returns the intensity forecast for interval i of truth by the
+// forecast issued at the start of interval issue.
+func (f *Forecast) forecast(issue, i int) loader.DataPoint {
+	date := f.truth.Data[i].StartDate
+	lead := max(date.Sub(f.truth.Data[issue].StartDate), 0)
+	spread := f.model.Error + f.model.Growth*lead.Hours()
+	intensity := f.base(i) * (1 + spread*f.noise[i])
+	return loader.DataPoint{
+		StartDate:       date,
+		CarbonIntensity: max(intensity, 0),
+	}
+}
+
+// base returns the error free forecast for interval i of truth: the source
+// forecast where it covers the interval, otherwise the lagged measurement.
+func (f *Forecast) base(i int) float64 {
+	date := f.truth.Data[i].StartDate
+	if f.model.Source != nil && date.Before(f.model.Source.EndDate()) {
+		if index, err := f.model.Source.GetIndexByDate(date); err == nil {
+			return f.model.Source.Data[index].CarbonIntensity
+		}
+	}
+	if f.model.Lag <= 0 {
+		return f.truth.Data[i].CarbonIntensity
+	}
+	index, err := f.truth.GetIndexByDate(date.Add(-f.model.Lag))
+	if err != nil {
+		index = 0
+	}
+	return f.truth.Data[index].CarbonIntensity
+}
+
+func (f *Forecast) String() string {
+	return f.model.String()
+}
@@ -0,0 +1,32 @@
+package forecast
+
+import (
+	"simulator/pkg/loader"
+	"sync"
+	"time"
+)
+
+// Model describes how forecasts depart from the measured carbon intensity.
+// The zero Model is a perfect forecast.
+type Model struct {
+	Error       float64        // Relative standard deviation of the error at zero lead time
+	Growth      float64        // Added relative standard deviation per hour of lead time
+	Correlation float64        // AR(1) correlation between errors of consecutive intervals
+	Lag         time.Duration  // Forecasts repeat the intensity measured this long before
+	Horizon     time.Duration  // How far ahead of its issue a forecast reaches, 0 for unlimited
+	Source      *loader.Loader // Forecast intensities replacing the measured ones, nil for none
+}
+
+// Forecast gives policies the intensity they would have seen from a forecast
+// issued at the time they decide, while accounting keeps the measured data.
+// A forecast is issued at the start of every interval of truth, and the latest
+// one is kept for the views of its interval to share.
+type Forecast struct {
+	truth *loader.Loader
+	model Model
+	noise []float64 // Standard normal error of each interval of truth
+
+	mu     sync.Mutex     // Guards issue and issued, views are taken concurrently
+	issue  int            // Interval of truth issued was issued at the start of
+	issued *loader.Loader // Latest forecast issued, as far ahead as views have reached
+}
@@ -0,0 +1,182 @@
+package forecast
+
+import (
+	"math"
+	"simulator/pkg/loader"
+	"sync"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2023, time.January, 2, 0, 0, 0, 0, time.UTC)
+
+// hourly returns a day of hourly data whose intensity is 100 plus ten times
+// the hour, so every interval can be told apart.
+func hourly() *loader.Loader {
+	data := make([]*loader.DataPoint, 24)
+	for i := range data {
+		data[i] = &loader.DataPoint{
+			StartDate:       start.Add(time.Duration(i) * time.Hour),
+			CarbonIntensity: 100 + 10*float64(i),
+		}
+	}
+	return loader.NewLoaderFromData("hourly", data, time.UTC)
+}
+
+// intensities returns the intensity of every entry of the loader.
+func intensities(l *loader.Loader) []float64 {
+	values := make([]float64, l.NumEntries())
+	for i, dataPoint := range l.Data[:l.NumEntries()] {
+		values[i] = dataPoint.CarbonIntensity
+	}
+	return values
+}
+
+func TestViewLag(t *testing.T) {
+	forecast := New(hourly(), Model{Lag: 2 * time.Hour}, 1)
+	tests := []struct {
+		name          string
+		issued, until time.Duration
+		want          []float64
+	}{
+		{name: "lagged measurements", issued: 5 * time.Hour, until: 8 * time.Hour, want: []float64{130, 140, 150, 160}},
+		{name: "lag before the data", issued: 0, until: 3 * time.Hour, want: []float64{100, 100, 100, 110}},
+		{name: "issued within an interval", issued: 5*time.Hour + 30*time.Minute, until: 6 * time.Hour, want: []float64{130, 140}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			view := forecast.View(start.Add(test.issued), start.Add(test.until))
+			got := intensities(view)
+			if len(got) != len(test.want) {
+				t.Fatalf("view holds %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("view holds %v, want %v", got, test.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestViewHorizon(t *testing.T) {
+	tests := []struct {
+		name          string
+		horizon       time.Duration
+		issued, until time.Duration
+		wantFirst     int // Hour the view starts at
+		wantEntries   int
+	}{
+		{name: "truncated", horizon: 3 * time.Hour, issued: 2 * time.Hour, until: 10 * time.Hour, wantFirst: 2, wantEntries: 4},
+		{name: "within the horizon", horizon: 3 * time.Hour, issued: 2 * time.Hour, until: 4 * time.Hour, wantFirst: 2, wantEntries: 3},
+		{name: "unlimited", horizon: 0, issued: 2 * time.Hour, until: 10 * time.Hour, wantFirst: 2, wantEntries: 9},
+		{name: "past the data", horizon: 0, issued: 20 * time.Hour, until: 30 * time.Hour, wantFirst: 20, wantEntries: 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			forecast := New(hourly(), Model{Horizon: test.horizon}, 1)
+			view := forecast.View(start.Add(test.issued), start.Add(test.until))
+			wantStart := start.Add(time.Duration(test.wantFirst) * time.Hour)
+			if view.NumEntries() != test.wantEntries || !view.StartDate().Equal(wantStart) {
+				t.Errorf("view holds %d entries from %v, want %d from %v", view.NumEntries(), view.StartDate(), test.wantEntries, wantStart)
+			}
+			if view.Interval() != time.Hour {
+				t.Errorf("view is sampled every %v, want an hour", view.Interval())
+			}
+		})
+	}
+}
+
+func TestViewErrorShrinks(t *testing.T) {
+	truth := hourly()
+	target := 12
+	forecast := New(truth, Model{Growth: 0.01, Correlation: 0.5}, 3)
+	previous := math.Inf(1)
+	for issue := 0; issue <= target; issue++ {
+		view := forecast.View(start.Add(time.Duration(issue)*time.Hour), start.Add(time.Duration(target)*time.Hour))
+		got := view.Data[view.NumEntries()-1]
+		if !got.StartDate.Equal(truth.Data[target].StartDate) {
+			t.Fatalf("view issued at hour %d ends at %v, want %v", issue, got.StartDate, truth.Data[target].StartDate)
+		}
+		spread := math.Abs(got.CarbonIntensity - truth.Data[target].CarbonIntensity)
+		if spread > previous {
+			t.Errorf("error at hour %d issued at hour %d is %f, more than %f issued an hour before", target, issue, spread, previous)
+		}
+		previous = spread
+	}
+	if previous != 0 {
+		t.Errorf("error at zero lead time is %f, want none without a base error", previous)
+	}
+}
+
+func TestViewSource(t *testing.T) {
+	data := make([]*loader.DataPoint, 6)
+	for i := range data {
+		data[i] = &loader.DataPoint{StartDate: start.Add(time.Duration(i) * time.Hour), CarbonIntensity: 50}
+	}
+	source := loader.NewLoaderFromData("source", data, time.UTC)
+	forecast := New(hourly(), Model{Source: source}, 1)
+	got := intensities(forecast.View(start.Add(4*time.Hour), start.Add(7*time.Hour)))
+	want := []float64{50, 50, 160, 170}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("view holds %v, want the source where it covers the data and truth after: %v", got, want)
+		}
+	}
+}
+
+func TestViewShared(t *testing.T) {
+	model := Model{Error: 0.1, Growth: 0.05, Correlation: 0.8}
+	forecast := New(hourly(), model, 5)
+	short := forecast.View(start.Add(3*time.Hour), start.Add(5*time.Hour))
+	shortValues := intensities(short)
+	long := forecast.View(start.Add(3*time.Hour+30*time.Minute), start.Add(12*time.Hour))
+	if short.NumEntries() != 3 || long.NumEntries() != 10 {
+		t.Fatalf("views hold %d and %d entries, want 3 and 10", short.NumEntries(), long.NumEntries())
+	}
+	// Both views are of the forecast issued at the start of hour 3
+	for i := range shortValues {
+		if long.Data[i].CarbonIntensity != shortValues[i] {
+			t.Errorf("views of one issue differ at entry %d: %f and %f", i, long.Data[i].CarbonIntensity, shortValues[i])
+		}
+	}
+	if short.NumEntries() != 3 || !short.EndDate().Equal(start.Add(6*time.Hour)) {
+		t.Errorf("extending the issue changed an earlier view to %d entries ending %v", short.NumEntries(), short.EndDate())
+	}
+	// Moving on to the next issue and back rebuilds the same forecast
+	forecast.View(start.Add(4*time.Hour), start.Add(12*time.Hour))
+	again := intensities(forecast.View(start.Add(3*time.Hour), start.Add(12*time.Hour)))
+	fresh := intensities(New(hourly(), model, 5).View(start.Add(3*time.Hour), start.Add(12*time.Hour)))
+	for i := range fresh {
+		if again[i] != long.Data[i].CarbonIntensity || fresh[i] != again[i] {
+			t.Errorf("reissued forecast differs at entry %d: %f, %f and fresh %f", i, again[i], long.Data[i].CarbonIntensity, fresh[i])
+		}
+	}
+}
+
+func TestViewConcurrent(t *testing.T) {
+	model := Model{Error: 0.1, Growth: 0.05, Correlation: 0.8}
+	forecast := New(hourly(), model, 7)
+	var wg sync.WaitGroup
+	views := make([]*loader.Loader, 24)
+	for i := range views {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			views[i] = forecast.View(start.Add(time.Duration(i%6)*time.Hour), start.Add(time.Duration(6+i%12)*time.Hour))
+		}(i)
+	}
+	wg.Wait()
+	for i, view := range views {
+		issue := i % 6
+		if view.NumEntries() != 6+i%12-issue+1 {
+			t.Fatalf("view %d holds %d entries, want %d", i, view.NumEntries(), 6+i%12-issue+1)
+		}
+		for j, dataPoint := range view.Data[:view.NumEntries()] {
+			if want := forecast.forecast(issue, issue+j); *dataPoint != want {
+				t.Errorf("view %d entry %d is %v, want %v", i, j, *dataPoint, want)
+			}
+		}
+	}
+}
@@ -4,44 +4,29 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"sync"
+	"slices"
+	"sort"
+	"strings"
 	"time"
-
-	"github.com/gocarina/gocsv"
 )
 
-var lock = &sync.Mutex{}
-var singleton *Loader
-
 func NewLoader(filename string) *Loader {
-	if singleton == nil {
-		lock.Lock()
-		defer lock.Unlock()
-		if singleton == nil {
-			singleton = &Loader{
-				filename: filename,
-			}
-			err := singleton.loadFromFile()
-			if err != nil {
-				log.Println("Error loading from file:", err)
-				singleton = nil
-				return nil
-			}
-		} else {
-			log.Println("Loader already initialized")
-		}
-	} else {
-		log.Println("Loader already initialized")
-	}
-	return singleton
+	return NewLoaderWithSchema(filename, Schema{})
 }
 
-func GetLoader() *Loader {
-	if singleton == nil {
-		log.Println("Loader not initialized")
+// NewLoaderWithSchema loads a data file laid out as the schema describes.
+func NewLoaderWithSchema(filename string, schema Schema) *Loader {
+	dataLoader := &Loader{
+		filename: filename,
+		location: time.UTC,
+		schema:   schema,
+	}
+	err := dataLoader.loadFromFile()
+	if err != nil {
+		log.Println("Error loading from file:", err)
 		return nil
 	}
-	return singleton
+	return dataLoader
 }
 
 func (l *Loader) loadFromFile() error {
@@ -53,8 +38,13 @@ func (l *Loader) loadFromFile() error {
 		return err
 	}
 	defer dataFile.Close()
-	if err := gocsv.UnmarshalFile(dataFile, &l.Data); err != nil {
-		log.Println("Error unmarshalling file:", err)
+	schema, err := l.schema.resolve(l.filename)
+	if err != nil {
+		log.Println("Error reading file:", err)
+		return err
+	}
+	if l.Data, l.marginal, err = schema.read(dataFile); err != nil {
+		log.Println("Error reading file:", err)
 		return err
 	}
 	l.numEntries = len(l.Data)
@@ -62,8 +52,17 @@ func (l *Loader) loadFromFile() error {
 		log.Println("No data found in file")
 		return fmt.Errorf("no data found in file")
 	}
-	l.startDate = l.Data[0].StartDate
-	log.Println("Data loaded successfully, number of entries:", l.numEntries)
+	// Timestamps may carry any UTC offset, so they are normalised to UTC and
+	// converted to the region's zone only where local time matters
+	for _, dataPoint := range l.Data {
+		dataPoint.StartDate = dataPoint.StartDate.UTC()
+	}
+	l.clean()
+	l.buildIndex()
+	log.Println("Data loaded successfully, number of entries:", l.numEntries, "sampled every", l.interval)
+	if !l.quality.Clean() {
+		log.Println("Data quality:", l.quality)
+	}
 	return nil
 }
 
@@ -76,7 +75,11 @@ func (l *Loader) PrintAllData() error {
 		return fmt.Errorf("no data to print")
 	}
 	for _, dataPoint := range l.Data {
-		fmt.Printf("StartDate: %s, CarbonIntensity: %f\n", dataPoint.StartDate.Format("2006-01-02 03:04 PM"), dataPoint.CarbonIntensity)
+		if l.marginal {
+			fmt.Printf("StartDate: %s, CarbonIntensity: %f, MarginalIntensity: %f\n", dataPoint.StartDate.In(l.location).Format("2006-01-02 03:04 PM"), dataPoint.CarbonIntensity, dataPoint.MarginalIntensity)
+			continue
+		}
+		fmt.Printf("StartDate: %s, CarbonIntensity: %f\n", dataPoint.StartDate.In(l.location).Format("2006-01-02 03:04 PM"), dataPoint.CarbonIntensity)
 	}
 	return nil
 }
@@ -87,8 +90,8 @@ func (l *Loader) GetIndexByDate(date time.Time) (int, error) {
 		return -1, fmt.Errorf("no data available")
 	}
 	// If the date is before the start date or after the last date in the data, return an error
-	// The last date is the start date of the last entry + 5 minutes
-	if date.Before(l.startDate) || date.After(l.Data[l.numEntries-1].StartDate.Add(5*time.Minute)) {
+	// The last date is the start date of the last entry plus the sampling interval
+	if date.Before(l.startDate) || date.After(l.EndDate()) {
 		return -1, fmt.Errorf("date out of range")
 	}
 	// Entries are sorted by date we can binary search for the date
@@ -121,9 +124,218 @@ func (l *Loader) StartDate() time.Time {
 	return l.startDate
 }
 
+// Location returns the local time zone of the region, UTC unless set.
+func (l *Loader) Location() *time.Location {
+	return l.location
+}
+
+func (l *Loader) SetLocation(location *time.Location) {
+	if location == nil {
+		location = time.UTC
+	}
+	l.location = location
+}
+
 func (l *Loader) EndDate() time.Time {
 	if l.numEntries == 0 {
 		return time.Time{}
 	}
-	return l.Data[l.numEntries-1].StartDate.Add(5 * time.Minute)
+	return l.Data[l.numEntries-1].StartDate.Add(l.interval)
+}
+
+// Interval returns the sampling interval of the data, the most common gap
+// between entries. The last entry covers one interval.
+func (l *Loader) Interval() time.Duration {
+	return l.interval
+}
+
+// SetInterval overrides the inferred sampling interval, for data too short to
+// infer it from such as a slice of a longer trace.
+func (l *Loader) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = inferInterval(l.Data)
+	}
+	l.interval = interval
+	l.findGaps()
+}
+
+// NewLoaderFromData wraps data points already in memory in a loader, sorting
+// them and dropping duplicates as loading a file does. The data holds marginal
+// rates when any point has one. The name stands in for the filename in String.
+func NewLoaderFromData(name string, data []*DataPoint, location *time.Location) *Loader {
+	dataLoader := &Loader{
+		filename: name,
+		Data:     data,
+	}
+	dataLoader.SetLocation(location)
+	dataLoader.clean()
+	dataLoader.marginal = slices.ContainsFunc(dataLoader.Data, func(dataPoint *DataPoint) bool {
+		return dataPoint.MarginalIntensity != 0
+	})
+	dataLoader.buildIndex()
+	return dataLoader
+}
+
+// buildIndex integrates the intensity up to the start of every entry so the
+// integral over any interval takes two lookups. Data must not change after,
+// other than by Append.
+func (l *Loader) buildIndex() {
+	l.cumulative = make([]float64, l.numEntries)
+	for i := 1; i < l.numEntries; i++ {
+		width := l.Data[i].StartDate.Sub(l.Data[i-1].StartDate).Seconds()
+		l.cumulative[i] = l.cumulative[i-1] + l.Data[i-1].CarbonIntensity*width
+	}
+}
+
+// Append adds entries after the last one, extending the integral index. The
+// entries must be in order and start after the data does.
+func (l *Loader) Append(data ...*DataPoint) error {
+	for _, dataPoint := range data {
+		if l.numEntries > 0 && !dataPoint.StartDate.After(l.Data[l.numEntries-1].StartDate) {
+			return fmt.Errorf("entry at %s does not follow the data", dataPoint.StartDate.Format(time.RFC3339))
+		}
+		cumulative := 0.0
+		if l.numEntries > 0 {
+			width := dataPoint.StartDate.Sub(l.Data[l.numEntries-1].StartDate).Seconds()
+			cumulative = l.cumulative[l.numEntries-1] + l.Data[l.numEntries-1].CarbonIntensity*width
+		} else {
+			l.startDate = dataPoint.StartDate
+		}
+		l.Data = append(l.Data, dataPoint)
+		l.cumulative = append(l.cumulative, cumulative)
+		l.numEntries++
+		l.marginal = l.marginal || dataPoint.MarginalIntensity != 0
+	}
+	return nil
+}
+
+// Slice returns a loader over the entries from first up to but not including
+// last. It shares the data and integral index, so it costs no copying, and
+// keeps the interval but not the quality report.
+func (l *Loader) Slice(first, last int) *Loader {
+	first, last = max(first, 0), min(last, l.numEntries)
+	slice := &Loader{
+		filename:   l.filename,
+		location:   l.location,
+		interval:   l.interval,
+		marginal:   l.marginal,
+		numEntries: max(last-first, 0),
+	}
+	if slice.numEntries > 0 {
+		slice.Data = l.Data[first:last:last]
+		slice.cumulative = l.cumulative[first:last:last]
+		slice.startDate = slice.Data[0].StartDate
+	}
+	return slice
+}
+
+// cumulativeAt returns the intensity integrated from the first entry to date,
+// which falls in entry idx. The last entry's intensity holds past the data.
+func (l *Loader) cumulativeAt(idx int, date time.Time) float64 {
+	return l.cumulative[idx] + l.Data[idx].CarbonIntensity*date.Sub(l.Data[idx].StartDate).Seconds()
+}
+
+// locate returns the entry holding date, stepping forward from hint when the
+// date lies at or after it and searching otherwise. Dates past the data fall
+// in the last entry.
+func (l *Loader) locate(date time.Time, hint int) (int, error) {
+	if date.Before(l.startDate) {
+		return -1, fmt.Errorf("date out of range")
+	}
+	if hint < 0 || hint >= l.numEntries || l.Data[hint].StartDate.After(date) {
+		hint = sort.Search(l.numEntries, func(i int) bool {
+			return l.Data[i].StartDate.After(date)
+		}) - 1
+	}
+	for hint < l.numEntries-1 && !l.Data[hint+1].StartDate.After(date) {
+		hint++
+	}
+	return hint, nil
+}
+
+// Integral returns the carbon intensity integrated from start to end, in
+// kgCO2/MWh seconds. The last entry's intensity holds until end, while an
+// interval starting after the data integrates to zero.
+func (l *Loader) Integral(start time.Time, end time.Time) (float64, error) {
+	if l.numEntries == 0 {
+		return 0, fmt.Errorf("no data available")
+	}
+	if !start.Before(end) || !start.Before(l.EndDate()) {
+		return 0, nil
+	}
+	first, err := l.locate(start, -1)
+	if err != nil {
+		return 0, err
+	}
+	last, err := l.locate(end, first)
+	if err != nil {
+		return 0, err
+	}
+	return l.cumulativeAt(last, end) - l.cumulativeAt(first, start), nil
+}
+
+// NewWindow returns a window of the given length at the start of the data.
+func (l *Loader) NewWindow(length time.Duration) *Window {
+	return &Window{
+		loader: l,
+		length: length,
+		first:  -1,
+	}
+}
+
+// Integral returns the intensity integrated over the window moved to start,
+// as Loader.Integral does. Moving the window back falls back to a search.
+func (w *Window) Integral(start time.Time) (float64, error) {
+	l := w.loader
+	end := start.Add(w.length)
+	if l.numEntries == 0 {
+		return 0, fmt.Errorf("no data available")
+	}
+	if !start.Before(end) || !start.Before(l.EndDate()) {
+		return 0, nil
+	}
+	first, err := l.locate(start, w.first)
+	if err != nil {
+		return 0, err
+	}
+	last, err := l.locate(end, max(w.last, first))
+	if err != nil {
+		return 0, err
+	}
+	w.first, w.last = first, last
+	return l.cumulativeAt(last, end) - l.cumulativeAt(first, start), nil
+}
+
+// HasMarginal reports whether the data holds marginal emission rates.
+func (l *Loader) HasMarginal() bool {
+	return l.marginal
+}
+
+// Signal returns a loader whose CarbonIntensity is the named emission factor,
+// one of Signals. Policies and accounting read CarbonIntensity, so handing
+// each the loader of its own signal is all it takes to switch between them.
+func (l *Loader) Signal(signal string) (*Loader, error) {
+	switch signal {
+	case "", "average":
+		return l, nil
+	case "marginal":
+		if !l.marginal {
+			return nil, fmt.Errorf("%s has no marginal emission rates", l.filename)
+		}
+		data := make([]*DataPoint, l.numEntries)
+		for i, dataPoint := range l.Data {
+			data[i] = &DataPoint{
+				StartDate:         dataPoint.StartDate,
+				CarbonIntensity:   dataPoint.MarginalIntensity,
+				MarginalIntensity: dataPoint.MarginalIntensity,
+			}
+		}
+		marginal := NewLoaderFromData(l.filename+" (marginal)", data, l.location)
+		marginal.SetInterval(l.interval)
+		marginal.quality = l.quality
+		marginal.marginal = true
+		return marginal, nil
+	default:
+		return nil, fmt.Errorf("invalid signal %q, please choose %s", signal, strings.Join(Signals, ", "))
+	}
 }
@@ -13,16 +13,46 @@ type LoaderInterface interface {
 	NumEntries() int
 	StartDate() time.Time
 	EndDate() time.Time
+	Interval() time.Duration
+	SetInterval(interval time.Duration)
+	Quality() Quality
+	CheckGaps(maxGap time.Duration) error
+	Resample(interval time.Duration, interpolation string) (*Loader, error)
+	HasMarginal() bool
+	Signal(signal string) (*Loader, error)
+	Location() *time.Location
+	SetLocation(location *time.Location)
+	Integral(start time.Time, end time.Time) (float64, error)
+	NewWindow(length time.Duration) *Window
+	Append(data ...*DataPoint) error
+	Slice(first, last int) *Loader
 }
 
 type Loader struct {
 	filename   string
 	startDate  time.Time
 	numEntries int
+	location   *time.Location // Local time zone of the region the data covers
+	interval   time.Duration  // Sampling interval, inferred from the data
+	schema     Schema         // Layout of the data file
+	quality    Quality        // Problems found in the data as it was loaded
+	marginal   bool           // Whether MarginalIntensity holds the data's marginal emission rates
+	cumulative []float64      // Intensity integrated from the first entry to the start of each entry, in kgCO2/MWh seconds
 	Data       []*DataPoint
 }
 
+// Window integrates the intensity over an interval of fixed length as it
+// slides forward through the data. Each slide steps both ends on from where
+// they were, so scanning every start of a job costs constant time per start.
+type Window struct {
+	loader *Loader
+	length time.Duration
+	first  int // Entry holding the start of the last interval integrated, -1 before the first
+	last   int // Entry holding the end of the last interval integrated
+}
+
 type DataPoint struct {
-	StartDate       time.Time `csv:"start_date"`
-	CarbonIntensity float64   `csv:"generated_rate_kg_per_mwh"`
+	StartDate         time.Time
+	CarbonIntensity   float64 // in kgCO2/MWh, whatever unit the data file reports in
+	MarginalIntensity float64 // Marginal emission rate in kgCO2/MWh, when the loader has one
 }
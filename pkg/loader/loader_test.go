@@ -0,0 +1,118 @@
+package loader_test
+
+import (
+	"math"
+	"simulator/pkg/loader"
+	"testing"
+	"time"
+)
+
+func TestAppend(t *testing.T) {
+	source := syntheticLoader(t, 6*time.Hour)
+	start := source.StartDate()
+	appended := loader.NewLoaderFromData("appended", nil, source.Location())
+	appended.SetInterval(source.Interval())
+	// Append in uneven batches, the integral index must match one built at once
+	for first := 0; first < source.NumEntries(); first += 7 {
+		last := min(first+7, source.NumEntries())
+		if err := appended.Append(source.Data[first:last]...); err != nil {
+			t.Fatalf("Append entries %d to %d: %v", first, last, err)
+		}
+	}
+	if appended.NumEntries() != source.NumEntries() || !appended.StartDate().Equal(start) || !appended.EndDate().Equal(source.EndDate()) {
+		t.Fatalf("appended loader holds %d entries from %v to %v, want %d from %v to %v", appended.NumEntries(), appended.StartDate(), appended.EndDate(), source.NumEntries(), start, source.EndDate())
+	}
+	for _, span := range [][2]time.Duration{{0, 6 * time.Hour}, {17 * time.Minute, 3 * time.Hour}, {5 * time.Hour, 8 * time.Hour}} {
+		want, err := source.Integral(start.Add(span[0]), start.Add(span[1]))
+		if err != nil {
+			t.Fatalf("Integral: %v", err)
+		}
+		got, err := appended.Integral(start.Add(span[0]), start.Add(span[1]))
+		if err != nil {
+			t.Fatalf("Integral: %v", err)
+		}
+		if math.Abs(got-want) > 1e-6*want {
+			t.Errorf("integral from %v to %v is %f, want %f", span[0], span[1], got, want)
+		}
+	}
+
+	tests := []struct {
+		name string
+		date time.Time
+	}{
+		{name: "same start as the last entry", date: source.Data[source.NumEntries()-1].StartDate},
+		{name: "before the last entry", date: start},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := appended.Append(&loader.DataPoint{StartDate: test.date}); err == nil {
+				t.Errorf("Append succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestSlice(t *testing.T) {
+	source := syntheticLoader(t, 6*time.Hour)
+	tests := []struct {
+		name        string
+		first, last int
+		wantFirst   int // Entry of source the slice starts at
+		wantEntries int
+	}{
+		{name: "middle", first: 12, last: 36, wantFirst: 12, wantEntries: 24},
+		{name: "whole", first: 0, last: source.NumEntries(), wantFirst: 0, wantEntries: source.NumEntries()},
+		{name: "clamped", first: -5, last: source.NumEntries() + 5, wantFirst: 0, wantEntries: source.NumEntries()},
+		{name: "empty", first: 20, last: 10, wantEntries: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			slice := source.Slice(test.first, test.last)
+			if slice.NumEntries() != test.wantEntries {
+				t.Fatalf("slice holds %d entries, want %d", slice.NumEntries(), test.wantEntries)
+			}
+			if test.wantEntries == 0 {
+				return
+			}
+			start := source.Data[test.wantFirst].StartDate
+			end := source.Data[test.wantFirst+test.wantEntries-1].StartDate.Add(source.Interval())
+			if !slice.StartDate().Equal(start) || !slice.EndDate().Equal(end) || slice.Interval() != source.Interval() {
+				t.Errorf("slice covers %v to %v every %v, want %v to %v every %v", slice.StartDate(), slice.EndDate(), slice.Interval(), start, end, source.Interval())
+			}
+			want, err := source.Integral(start.Add(time.Minute), end.Add(-time.Minute))
+			if err != nil {
+				t.Fatalf("Integral: %v", err)
+			}
+			got, err := slice.Integral(start.Add(time.Minute), end.Add(-time.Minute))
+			if err != nil {
+				t.Fatalf("Integral: %v", err)
+			}
+			if math.Abs(got-want) > 1e-6*want {
+				t.Errorf("integral over the slice is %f, want %f", got, want)
+			}
+		})
+	}
+}
+
+func TestSliceAfterAppend(t *testing.T) {
+	source := syntheticLoader(t, 2*time.Hour)
+	grown := loader.NewLoaderFromData("grown", nil, source.Location())
+	grown.SetInterval(source.Interval())
+	if err := grown.Append(source.Data[:10]...); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	slice := grown.Slice(0, 5)
+	if err := grown.Append(source.Data[10:]...); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	// Appending to the loader must not reach into slices taken before
+	if err := slice.Append(source.Data[10]); err != nil {
+		t.Fatalf("Append to slice: %v", err)
+	}
+	if grown.NumEntries() != source.NumEntries() || grown.Data[5] != source.Data[5] {
+		t.Errorf("appending to a slice changed the loader it was taken from")
+	}
+	if slice.NumEntries() != 6 || slice.Data[5] != source.Data[10] {
+		t.Errorf("slice holds %d entries ending at %v, want 6 ending at %v", slice.NumEntries(), slice.Data[slice.NumEntries()-1].StartDate, source.Data[10].StartDate)
+	}
+}
@@ -0,0 +1,249 @@
+package loader
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Interpolations lists the ways Resample fills times between entries.
+// "previous" holds each entry until the next, "linear" joins entries with
+// straight lines and "cubic" with a monotone cubic that never overshoots the
+// entries on either side.
+var Interpolations = []string{"previous", "linear", "cubic"}
+
+func (g Gap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
+
+func (g Gap) String() string {
+	return fmt.Sprintf("%s to %s (%v)", g.Start.Format(time.RFC3339), g.End.Format(time.RFC3339), g.Duration())
+}
+
+// Clean reports whether the data had no problems.
+func (q Quality) Clean() bool {
+	return q.OutOfOrder == 0 && len(q.Duplicates) == 0 && len(q.Gaps) == 0
+}
+
+// LongestGap returns the longest gap, the zero Gap when there is none.
+func (q Quality) LongestGap() Gap {
+	var longest Gap
+	for _, gap := range q.Gaps {
+		if gap.Duration() > longest.Duration() {
+			longest = gap
+		}
+	}
+	return longest
+}
+
+// MissingTime returns the total time the gaps cover.
+func (q Quality) MissingTime() time.Duration {
+	missing := time.Duration(0)
+	for _, gap := range q.Gaps {
+		missing += gap.Duration()
+	}
+	return missing
+}
+
+func (q Quality) String() string {
+	if q.Clean() {
+		return "no gaps, duplicates or out-of-order rows"
+	}
+	problems := make([]string, 0, 3)
+	if len(q.Gaps) > 0 {
+		problems = append(problems, fmt.Sprintf("%d gaps missing %v, the longest %v", len(q.Gaps), q.MissingTime(), q.LongestGap()))
+	}
+	if len(q.Duplicates) > 0 {
+		problems = append(problems, fmt.Sprintf("%d duplicate timestamps", len(q.Duplicates)))
+	}
+	if q.OutOfOrder > 0 {
+		problems = append(problems, fmt.Sprintf("%d out-of-order rows", q.OutOfOrder))
+	}
+	return strings.Join(problems, ", ")
+}
+
+// clean sorts the data, drops all but the first entry at each timestamp and
+// records what it found, then infers the sampling interval and finds gaps.
+func (l *Loader) clean() {
+	l.quality = Quality{Rows: len(l.Data)}
+	for i := 1; i < len(l.Data); i++ {
+		if l.Data[i].StartDate.Before(l.Data[i-1].StartDate) {
+			l.quality.OutOfOrder++
+		}
+	}
+	if l.quality.OutOfOrder > 0 {
+		slices.SortStableFunc(l.Data, func(a, b *DataPoint) int {
+			return a.StartDate.Compare(b.StartDate)
+		})
+	}
+	kept := l.Data[:0]
+	for _, dataPoint := range l.Data {
+		if len(kept) > 0 && kept[len(kept)-1].StartDate.Equal(dataPoint.StartDate) {
+			if len(l.quality.Duplicates) == 0 || !l.quality.Duplicates[len(l.quality.Duplicates)-1].Equal(dataPoint.StartDate) {
+				l.quality.Duplicates = append(l.quality.Duplicates, dataPoint.StartDate)
+			}
+			continue
+		}
+		kept = append(kept, dataPoint)
+	}
+	l.Data = kept
+	l.numEntries = len(l.Data)
+	if l.numEntries > 0 {
+		l.startDate = l.Data[0].StartDate
+	}
+	l.interval = inferInterval(l.Data)
+	l.findGaps()
+}
+
+// findGaps records every stretch between entries longer than the interval.
+func (l *Loader) findGaps() {
+	l.quality.Gaps = nil
+	for i := 1; i < l.numEntries; i++ {
+		covered := l.Data[i-1].StartDate.Add(l.interval)
+		if l.Data[i].StartDate.After(covered) {
+			l.quality.Gaps = append(l.quality.Gaps, Gap{Start: covered, End: l.Data[i].StartDate})
+		}
+	}
+}
+
+// Quality returns the problems found in the data as it was loaded.
+func (l *Loader) Quality() Quality {
+	return l.quality
+}
+
+// CheckGaps returns an error naming the first gap longer than maxGap, for
+// runs that must not simulate over missing data.
+func (l *Loader) CheckGaps(maxGap time.Duration) error {
+	for _, gap := range l.quality.Gaps {
+		if gap.Duration() > maxGap {
+			return fmt.Errorf("%s has a gap longer than %v from %v", l.filename, maxGap, gap)
+		}
+	}
+	return nil
+}
+
+// Resample returns a loader with an entry every interval from the start of
+// the data to its end, each interpolated from the entries around it. The
+// resampled loader keeps the original's quality report, gaps included, so
+// they are still reported after they have been filled.
+func (l *Loader) Resample(interval time.Duration, interpolation string) (*Loader, error) {
+	if l.numEntries == 0 {
+		return nil, fmt.Errorf("no data to resample")
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("resampling interval must be positive, got %v", interval)
+	}
+	average, err := l.interpolator(interpolation, func(dataPoint *DataPoint) float64 {
+		return dataPoint.CarbonIntensity
+	})
+	if err != nil {
+		return nil, err
+	}
+	marginal, _ := l.interpolator(interpolation, func(dataPoint *DataPoint) float64 {
+		return dataPoint.MarginalIntensity
+	})
+	data := make([]*DataPoint, 0, int(l.EndDate().Sub(l.startDate)/interval)+1)
+	idx := 0
+	for date := l.startDate; date.Before(l.EndDate()); date = date.Add(interval) {
+		for idx < l.numEntries-1 && !l.Data[idx+1].StartDate.After(date) {
+			idx++
+		}
+		dataPoint := &DataPoint{StartDate: date, CarbonIntensity: max(average(idx, date), 0)}
+		if l.marginal {
+			// Marginal rates may fall below zero when curtailed renewables are on the margin
+			dataPoint.MarginalIntensity = marginal(idx, date)
+		}
+		data = append(data, dataPoint)
+	}
+	resampled := NewLoaderFromData(fmt.Sprintf("%s resampled every %v (%s)", l.filename, interval, interpolation), data, l.location)
+	resampled.SetInterval(interval)
+	resampled.quality = l.quality
+	resampled.marginal = l.marginal
+	return resampled, nil
+}
+
+// interpolator returns the interpolation of the intensity that get reads
+// from each entry, evaluated at a date falling in entry idx.
+func (l *Loader) interpolator(interpolation string, get func(*DataPoint) float64) (func(idx int, date time.Time) float64, error) {
+	switch interpolation {
+	case "", "previous":
+		return func(idx int, date time.Time) float64 {
+			return get(l.Data[idx])
+		}, nil
+	case "linear":
+		return func(idx int, date time.Time) float64 {
+			return l.linear(get, idx, date)
+		}, nil
+	case "cubic":
+		slopes := l.monotoneSlopes(get)
+		return func(idx int, date time.Time) float64 {
+			return l.cubic(get, slopes, idx, date)
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid interpolation %q, please choose %s", interpolation, strings.Join(Interpolations, ", "))
+	}
+}
+
+// fraction returns how far date lies between entry idx and the next.
+func (l *Loader) fraction(idx int, date time.Time) float64 {
+	return date.Sub(l.Data[idx].StartDate).Seconds() / l.Data[idx+1].StartDate.Sub(l.Data[idx].StartDate).Seconds()
+}
+
+func (l *Loader) linear(get func(*DataPoint) float64, idx int, date time.Time) float64 {
+	if idx >= l.numEntries-1 {
+		return get(l.Data[idx])
+	}
+	t := l.fraction(idx, date)
+	return (1-t)*get(l.Data[idx]) + t*get(l.Data[idx+1])
+}
+
+// monotoneSlopes returns the slope at each entry, per second, of the
+// Fritsch-Carlson monotone cubic through the entries.
+func (l *Loader) monotoneSlopes(get func(*DataPoint) float64) []float64 {
+	n := l.numEntries
+	slopes := make([]float64, n)
+	if n < 2 {
+		return slopes
+	}
+	secants := make([]float64, n-1)
+	for i := range secants {
+		width := l.Data[i+1].StartDate.Sub(l.Data[i].StartDate).Seconds()
+		secants[i] = (get(l.Data[i+1]) - get(l.Data[i])) / width
+	}
+	slopes[0], slopes[n-1] = secants[0], secants[n-2]
+	for i := 1; i < n-1; i++ {
+		if secants[i-1]*secants[i] <= 0 {
+			// A local extreme stays flat so the curve does not overshoot it
+			continue
+		}
+		slopes[i] = (secants[i-1] + secants[i]) / 2
+	}
+	for i, secant := range secants {
+		if secant == 0 {
+			slopes[i], slopes[i+1] = 0, 0
+			continue
+		}
+		alpha, beta := slopes[i]/secant, slopes[i+1]/secant
+		if norm := math.Hypot(alpha, beta); norm > 3 {
+			slopes[i] = 3 * alpha / norm * secant
+			slopes[i+1] = 3 * beta / norm * secant
+		}
+	}
+	return slopes
+}
+
+// cubic evaluates the cubic Hermite between entry idx and the next.
+func (l *Loader) cubic(get func(*DataPoint) float64, slopes []float64, idx int, date time.Time) float64 {
+	if idx >= l.numEntries-1 {
+		return get(l.Data[idx])
+	}
+	width := l.Data[idx+1].StartDate.Sub(l.Data[idx].StartDate).Seconds()
+	t := l.fraction(idx, date)
+	t2, t3 := t*t, t*t*t
+	return (2*t3-3*t2+1)*get(l.Data[idx]) +
+		(t3-2*t2+t)*width*slopes[idx] +
+		(-2*t3+3*t2)*get(l.Data[idx+1]) +
+		(t3-t2)*width*slopes[idx+1]
+}
@@ -0,0 +1,21 @@
+package loader
+
+import "time"
+
+// Gap is a stretch of time no entry covers: each entry covers one sampling
+// interval, so a gap runs from the end of one entry to the start of the next.
+type Gap struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Quality reports the problems found in data as it was loaded. The loader
+// sorts out-of-order rows and keeps the first of each duplicate timestamp,
+// while gaps are left for GetIndexByDate to fill with the previous entry
+// unless the data is resampled.
+type Quality struct {
+	Rows       int         // Rows read, before sorting and removing duplicates
+	OutOfOrder int         // Rows earlier than the row before them
+	Duplicates []time.Time // Timestamps appearing more than once
+	Gaps       []Gap
+}
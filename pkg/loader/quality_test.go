@@ -0,0 +1,208 @@
+package loader_test
+
+import (
+	"math"
+	"simulator/pkg/loader"
+	"simulator/pkg/synthetic"
+	"slices"
+	"testing"
+	"time"
+)
+
+// syntheticLoader returns a trace of the given length every five minutes.
+func syntheticLoader(t *testing.T, duration time.Duration) *loader.Loader {
+	t.Helper()
+	model := synthetic.Default()
+	model.Duration = duration
+	dataLoader, err := synthetic.NewLoader(model, 1)
+	if err != nil {
+		t.Fatalf("generating trace: %v", err)
+	}
+	return dataLoader
+}
+
+// withoutEntries returns a loader over the source's data with the entries at
+// the given indices left out.
+func withoutEntries(source *loader.Loader, removed ...int) *loader.Loader {
+	data := make([]*loader.DataPoint, 0, source.NumEntries())
+	for i, dataPoint := range source.Data {
+		if !slices.Contains(removed, i) {
+			copied := *dataPoint
+			data = append(data, &copied)
+		}
+	}
+	return loader.NewLoaderFromData("trace with gaps", data, source.Location())
+}
+
+func TestFindGaps(t *testing.T) {
+	source := syntheticLoader(t, 24*time.Hour)
+	start := source.StartDate()
+	at := func(entry int) time.Time {
+		return start.Add(time.Duration(entry) * 5 * time.Minute)
+	}
+	tests := []struct {
+		name     string
+		removed  []int
+		interval time.Duration // Overrides the inferred interval when set
+		want     []loader.Gap
+	}{
+		{name: "complete", want: nil},
+		{name: "one missing entry", removed: []int{10}, want: []loader.Gap{{Start: at(10), End: at(11)}}},
+		{name: "run of missing entries", removed: []int{20, 21, 22}, want: []loader.Gap{{Start: at(20), End: at(23)}}},
+		{
+			name:    "separate gaps",
+			removed: []int{5, 100, 101},
+			want:    []loader.Gap{{Start: at(5), End: at(6)}, {Start: at(100), End: at(102)}},
+		},
+		{name: "missing last entry leaves no gap", removed: []int{287}, want: nil},
+		{name: "interval wider than the gap", removed: []int{10}, interval: 10 * time.Minute, want: nil},
+		{
+			name:     "gap longer than a wider interval",
+			removed:  []int{2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19},
+			interval: 30 * time.Minute,
+			want:     []loader.Gap{{Start: at(1).Add(30 * time.Minute), End: at(20)}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dataLoader := withoutEntries(source, test.removed...)
+			if test.interval > 0 {
+				dataLoader.SetInterval(test.interval)
+			}
+			if got := dataLoader.FindGaps(); !slices.Equal(got, test.want) {
+				t.Errorf("FindGaps() = %v, want %v", got, test.want)
+			}
+			if got := dataLoader.Quality().Gaps; !slices.Equal(got, test.want) {
+				t.Errorf("Quality().Gaps = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResample(t *testing.T) {
+	source := syntheticLoader(t, 24*time.Hour)
+	gappy := withoutEntries(source, 30, 31, 32, 33, 200)
+	tests := []struct {
+		name          string
+		source        *loader.Loader
+		interval      time.Duration
+		interpolation string
+		// want is the resampled value at date falling in source entry idx,
+		// or NaN when the value only has to lie between its neighbours
+		want func(source *loader.Loader, idx int, date time.Time) float64
+	}{
+		{
+			name:     "same interval keeps the data",
+			source:   source,
+			interval: 5 * time.Minute,
+			want: func(source *loader.Loader, idx int, date time.Time) float64 {
+				return source.Data[idx].CarbonIntensity
+			},
+		},
+		{
+			name:          "previous holds each entry",
+			source:        gappy,
+			interval:      time.Minute,
+			interpolation: "previous",
+			want: func(source *loader.Loader, idx int, date time.Time) float64 {
+				return source.Data[idx].CarbonIntensity
+			},
+		},
+		{
+			name:          "linear joins entries",
+			source:        gappy,
+			interval:      time.Minute,
+			interpolation: "linear",
+			want: func(source *loader.Loader, idx int, date time.Time) float64 {
+				if idx == source.NumEntries()-1 {
+					return source.Data[idx].CarbonIntensity
+				}
+				from, to := source.Data[idx], source.Data[idx+1]
+				t := float64(date.Sub(from.StartDate)) / float64(to.StartDate.Sub(from.StartDate))
+				return from.CarbonIntensity + t*(to.CarbonIntensity-from.CarbonIntensity)
+			},
+		},
+		{
+			name:          "cubic never overshoots",
+			source:        gappy,
+			interval:      time.Minute,
+			interpolation: "cubic",
+			want: func(source *loader.Loader, idx int, date time.Time) float64 {
+				if date.Equal(source.Data[idx].StartDate) || idx == source.NumEntries()-1 {
+					return source.Data[idx].CarbonIntensity
+				}
+				return math.NaN()
+			},
+		},
+		{
+			name:          "coarser interval samples entries",
+			source:        source,
+			interval:      time.Hour,
+			interpolation: "linear",
+			want: func(source *loader.Loader, idx int, date time.Time) float64 {
+				return source.Data[idx].CarbonIntensity
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resampled, err := test.source.Resample(test.interval, test.interpolation)
+			if err != nil {
+				t.Fatalf("Resample: %v", err)
+			}
+			span := test.source.EndDate().Sub(test.source.StartDate())
+			wantEntries := int((span + test.interval - 1) / test.interval)
+			if resampled.NumEntries() != wantEntries {
+				t.Fatalf("resampled to %d entries, want %d", resampled.NumEntries(), wantEntries)
+			}
+			if resampled.Interval() != test.interval {
+				t.Errorf("resampled interval %v, want %v", resampled.Interval(), test.interval)
+			}
+			if !slices.Equal(resampled.Quality().Gaps, test.source.Quality().Gaps) {
+				t.Errorf("resampling changed the reported gaps")
+			}
+			for i, dataPoint := range resampled.Data {
+				date := test.source.StartDate().Add(time.Duration(i) * test.interval)
+				if !dataPoint.StartDate.Equal(date) {
+					t.Fatalf("entry %d starts at %v, want %v", i, dataPoint.StartDate, date)
+				}
+				idx, err := test.source.GetIndexByDate(date)
+				if err != nil {
+					t.Fatalf("GetIndexByDate(%v): %v", date, err)
+				}
+				want := test.want(test.source, idx, date)
+				if math.IsNaN(want) {
+					low := min(test.source.Data[idx].CarbonIntensity, test.source.Data[idx+1].CarbonIntensity)
+					high := max(test.source.Data[idx].CarbonIntensity, test.source.Data[idx+1].CarbonIntensity)
+					if dataPoint.CarbonIntensity < low-1e-9 || dataPoint.CarbonIntensity > high+1e-9 {
+						t.Fatalf("entry %d at %v is %f, outside its neighbours %f and %f", i, date, dataPoint.CarbonIntensity, low, high)
+					}
+					continue
+				}
+				if math.Abs(dataPoint.CarbonIntensity-want) > 1e-9 {
+					t.Fatalf("entry %d at %v is %f, want %f", i, date, dataPoint.CarbonIntensity, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResampleErrors(t *testing.T) {
+	source := syntheticLoader(t, 6*time.Hour)
+	tests := []struct {
+		name          string
+		interval      time.Duration
+		interpolation string
+	}{
+		{name: "zero interval", interval: 0},
+		{name: "negative interval", interval: -time.Minute},
+		{name: "unknown interpolation", interval: time.Minute, interpolation: "spline"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := source.Resample(test.interval, test.interpolation); err == nil {
+				t.Errorf("Resample(%v, %q) succeeded, want an error", test.interval, test.interpolation)
+			}
+		})
+	}
+}
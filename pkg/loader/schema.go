@@ -0,0 +1,365 @@
+package loader
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Providers lists the carbon intensity providers whose exports the loader
+// reads without a column mapping.
+var Providers = []string{"singularity", "electricitymaps", "watttime", "ukgrid"}
+
+// DataFormats lists the carbon data file formats the loader reads.
+var DataFormats = []string{"csv", "json"}
+
+// Signals lists the emission factors a loader can give: the average rate of
+// the generation mix, or the marginal rate of the generator that would meet
+// extra demand.
+var Signals = []string{"average", "marginal"}
+
+// Units lists the intensity units the loader converts from.
+var Units = []Unit{KgPerMWh, GPerKWh, LbsPerMWh}
+
+// DefaultInterval is the sampling interval assumed for data too short to
+// infer one from.
+const DefaultInterval = 5 * time.Minute
+
+var providers = map[string]providerSchemas{
+	// The format of data/collected, fetched from the Singularity API, with
+	// marginal rates alongside when they were fetched too
+	"singularity": {
+		csv:  Schema{Time: "start_date", Intensity: "generated_rate_kg_per_mwh", Marginal: "marginal_rate_kg_per_mwh", Unit: KgPerMWh},
+		json: Schema{Records: "data", Time: "start_date", Intensity: "data.generated_rate_kg_per_mwh", Marginal: "data.marginal_rate_kg_per_mwh", Unit: KgPerMWh},
+	},
+	// Electricity Maps CSV downloads and the carbon-intensity/history API
+	"electricitymaps": {
+		csv:  Schema{Time: "Datetime (UTC)", Intensity: "Carbon Intensity gCO₂eq/kWh (LCA)", Unit: GPerKWh},
+		json: Schema{Records: "history", Time: "datetime", Intensity: "carbonIntensity", Unit: GPerKWh},
+	},
+	// WattTime marginal operating emission rates from the historical API.
+	// WattTime publishes no average rates, so the marginal ones stand in
+	"watttime": {
+		csv:  Schema{Time: "point_time", Intensity: "value", Marginal: "value", Unit: LbsPerMWh},
+		json: Schema{Records: "data", Time: "point_time", Intensity: "value", Marginal: "value", Unit: LbsPerMWh},
+	},
+	// The GB Carbon Intensity API of the National Grid ESO
+	"ukgrid": {
+		json: Schema{Records: "data", Time: "from", TimeLayout: "2006-01-02T15:04Z", Intensity: "intensity.actual", Unit: GPerKWh},
+	},
+}
+
+// InferDataFormat guesses the carbon data format from the file extension.
+func InferDataFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	default:
+		return "csv"
+	}
+}
+
+// Validate checks the names in the schema without reading any data.
+func (s Schema) Validate() error {
+	if s.Provider != "" && !slices.Contains(Providers, s.Provider) {
+		return fmt.Errorf("invalid data provider %q, please choose %s", s.Provider, strings.Join(Providers, ", "))
+	}
+	if s.Format != "" && !slices.Contains(DataFormats, s.Format) {
+		return fmt.Errorf("invalid data format %q, please choose %s", s.Format, strings.Join(DataFormats, ", "))
+	}
+	if s.Unit != "" && !slices.Contains(Units, s.Unit) {
+		names := make([]string, len(Units))
+		for i, unit := range Units {
+			names[i] = string(unit)
+		}
+		return fmt.Errorf("invalid intensity unit %q, please choose %s", s.Unit, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// resolve fills the fields left empty from the provider's layout for the
+// format of the file at path.
+func (s Schema) resolve(path string) (Schema, error) {
+	if err := s.Validate(); err != nil {
+		return Schema{}, err
+	}
+	if s.Provider == "" {
+		s.Provider = "singularity"
+	}
+	if s.Format == "" {
+		s.Format = InferDataFormat(path)
+	}
+	layout := providers[s.Provider].csv
+	if s.Format == "json" {
+		layout = providers[s.Provider].json
+	}
+	if layout.Time == "" && (s.Time == "" || s.Intensity == "") {
+		return Schema{}, fmt.Errorf("provider %s has no %s format, please give the time and intensity columns", s.Provider, s.Format)
+	}
+	// Records only follow the provider when its columns are used too, so a
+	// custom mapping can read an array at the top of the file
+	if s.Time == "" && s.Records == "" {
+		s.Records = layout.Records
+	}
+	s.optionalMarginal = s.Marginal == "" && s.Time == "" && s.Intensity == ""
+	if s.optionalMarginal {
+		s.Marginal = layout.Marginal
+	}
+	for _, field := range []struct {
+		value  *string
+		preset string
+	}{
+		{&s.Time, layout.Time},
+		{&s.TimeLayout, layout.TimeLayout},
+		{&s.Intensity, layout.Intensity},
+	} {
+		if *field.value == "" {
+			*field.value = field.preset
+		}
+	}
+	if s.Unit == "" {
+		s.Unit = layout.Unit
+	}
+	if s.Unit == "" {
+		s.Unit = KgPerMWh
+	}
+	return s, nil
+}
+
+// read parses every data point in r, converting intensities to kgCO2/MWh,
+// and reports whether the data held marginal rates.
+func (s Schema) read(r io.Reader) ([]*DataPoint, bool, error) {
+	var data []*DataPoint
+	var marginal bool
+	var err error
+	switch s.Format {
+	case "csv":
+		data, marginal, err = s.readCSV(r)
+	case "json":
+		data, marginal, err = s.readJSON(r)
+	default:
+		return nil, false, fmt.Errorf("invalid data format %q, please choose %s", s.Format, strings.Join(DataFormats, ", "))
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	scale := s.Unit.toKgPerMWh()
+	for _, dataPoint := range data {
+		dataPoint.CarbonIntensity *= scale
+		dataPoint.MarginalIntensity *= scale
+	}
+	return data, marginal, nil
+}
+
+// toKgPerMWh returns the factor converting the unit to kgCO2/MWh.
+func (u Unit) toKgPerMWh() float64 {
+	switch u {
+	case LbsPerMWh:
+		return 0.45359237
+	default:
+		// A gram per kilowatt hour is a kilogram per megawatt hour
+		return 1
+	}
+}
+
+// readCSV reads a CSV file with a header row, matching column names without
+// regard to case or surrounding space.
+func (s Schema) readCSV(r io.Reader) ([]*DataPoint, bool, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading data header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Exports written on Windows may start with a byte order mark
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	timeColumn, ok := columns[strings.ToLower(s.Time)]
+	if !ok {
+		return nil, false, fmt.Errorf("data has no %q column", s.Time)
+	}
+	intensityColumn, ok := columns[strings.ToLower(s.Intensity)]
+	if !ok {
+		return nil, false, fmt.Errorf("data has no %q column", s.Intensity)
+	}
+	marginalColumn, marginal := columns[strings.ToLower(s.Marginal)]
+	if s.Marginal != "" && !marginal && !s.optionalMarginal {
+		return nil, false, fmt.Errorf("data has no %q column", s.Marginal)
+	}
+
+	data := make([]*DataPoint, 0)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("error reading data: %w", err)
+		}
+		if timeColumn >= len(record) || intensityColumn >= len(record) || (marginal && marginalColumn >= len(record)) {
+			return nil, false, fmt.Errorf("line %d: missing columns", line)
+		}
+		value := strings.TrimSpace(record[intensityColumn])
+		if value == "" {
+			continue
+		}
+		startDate, err := s.parseTime(strings.TrimSpace(record[timeColumn]))
+		if err != nil {
+			return nil, false, fmt.Errorf("line %d: %w", line, err)
+		}
+		intensity, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("line %d: invalid intensity %q", line, value)
+		}
+		dataPoint := &DataPoint{StartDate: startDate, CarbonIntensity: intensity}
+		if marginal {
+			value := strings.TrimSpace(record[marginalColumn])
+			if dataPoint.MarginalIntensity, err = strconv.ParseFloat(value, 64); err != nil {
+				return nil, false, fmt.Errorf("line %d: invalid marginal rate %q", line, value)
+			}
+		}
+		data = append(data, dataPoint)
+	}
+	return data, marginal, nil
+}
+
+// readJSON reads an array of records, found at the schema's records path.
+// Records without an intensity, such as periods a provider has not yet
+// measured, are skipped.
+func (s Schema) readJSON(r io.Reader) ([]*DataPoint, bool, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, false, fmt.Errorf("error parsing data: %w", err)
+	}
+	records, ok := jsonField(document, s.Records).([]any)
+	if !ok {
+		if s.Records == "" {
+			return nil, false, fmt.Errorf("data is not an array of records")
+		}
+		return nil, false, fmt.Errorf("data has no array of records at %q", s.Records)
+	}
+	// Marginal rates are read when the first record with an intensity has one
+	marginal := false
+	for _, record := range records {
+		if jsonField(record, s.Intensity) != nil {
+			marginal = s.Marginal != "" && jsonField(record, s.Marginal) != nil
+			break
+		}
+	}
+	if s.Marginal != "" && !marginal && !s.optionalMarginal {
+		return nil, false, fmt.Errorf("data has no %q field", s.Marginal)
+	}
+
+	data := make([]*DataPoint, 0, len(records))
+	for index, record := range records {
+		value := jsonField(record, s.Intensity)
+		if value == nil {
+			continue
+		}
+		intensity, err := strconv.ParseFloat(jsonText(value), 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("record %d: invalid intensity %q", index+1, jsonText(value))
+		}
+		when := jsonField(record, s.Time)
+		if when == nil {
+			return nil, false, fmt.Errorf("record %d: missing %q", index+1, s.Time)
+		}
+		startDate, err := s.parseTime(jsonText(when))
+		if err != nil {
+			return nil, false, fmt.Errorf("record %d: %w", index+1, err)
+		}
+		dataPoint := &DataPoint{StartDate: startDate, CarbonIntensity: intensity}
+		if marginal {
+			value := jsonField(record, s.Marginal)
+			if value == nil {
+				return nil, false, fmt.Errorf("record %d: missing %q", index+1, s.Marginal)
+			}
+			if dataPoint.MarginalIntensity, err = strconv.ParseFloat(jsonText(value), 64); err != nil {
+				return nil, false, fmt.Errorf("record %d: invalid marginal rate %q", index+1, jsonText(value))
+			}
+		}
+		data = append(data, dataPoint)
+	}
+	return data, marginal, nil
+}
+
+// jsonField follows a dotted path of object keys from value, returning nil
+// when any step is missing. The empty path returns value itself.
+func jsonField(value any, path string) any {
+	if path == "" {
+		return value
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// jsonText returns a decoded JSON string or number as text.
+func jsonText(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// parseTime reads a time in the schema's layout, or else as RFC 3339,
+// "2006-01-02 15:04:05" in UTC, or Unix seconds.
+func (s Schema) parseTime(value string) (time.Time, error) {
+	if s.TimeLayout != "" {
+		parsed, err := time.Parse(s.TimeLayout, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%q does not match the time layout %q", value, s.TimeLayout)
+		}
+		return parsed, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return parsed, nil
+	}
+	if parsed, err := time.Parse(time.DateTime, value); err == nil {
+		return parsed, nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		whole, fraction := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(fraction*1e9)), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or Unix seconds", value)
+}
+
+// inferInterval returns the most common gap between consecutive entries,
+// the shorter on ties, or DefaultInterval when there is no gap to go by.
+func inferInterval(data []*DataPoint) time.Duration {
+	counts := make(map[time.Duration]int)
+	for i := 1; i < len(data); i++ {
+		if gap := data[i].StartDate.Sub(data[i-1].StartDate); gap > 0 {
+			counts[gap]++
+		}
+	}
+	interval, most := DefaultInterval, 0
+	for gap, count := range counts {
+		if count > most || (count == most && gap < interval) {
+			interval, most = gap, count
+		}
+	}
+	return interval
+}
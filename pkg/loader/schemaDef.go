@@ -0,0 +1,34 @@
+package loader
+
+// Unit is a carbon intensity unit data files may report in. The loader
+// converts every intensity to kgCO2/MWh as it reads it.
+type Unit string
+
+const (
+	KgPerMWh  Unit = "kg/MWh"
+	GPerKWh   Unit = "g/kWh"
+	LbsPerMWh Unit = "lbs/MWh"
+)
+
+// Schema describes where a carbon data file keeps the start of each interval
+// and its intensity. Fields left empty take the provider's layout for the
+// file's format.
+type Schema struct {
+	Provider   string // One of Providers, singularity when empty
+	Format     string // csv or json, inferred from the extension when empty
+	Records    string // Dotted path to the array of records in JSON files, empty when the file is the array
+	Time       string // Column, or dotted field path in JSON records, holding each interval's start
+	TimeLayout string // Go layout of the times, RFC 3339, "2006-01-02 15:04:05" or Unix seconds when empty
+	Intensity  string // Column, or dotted field path in JSON records, holding the intensity
+	Marginal   string // Column, or dotted field path in JSON records, holding the marginal emission rate
+	Unit       Unit   // Unit of the intensity and marginal emission rate
+
+	optionalMarginal bool // Whether the marginal column comes from the provider, which need not fill it
+}
+
+// providerSchemas holds a provider's export layout in each format it offers.
+// A format the provider does not offer has an empty schema.
+type providerSchemas struct {
+	csv  Schema
+	json Schema
+}
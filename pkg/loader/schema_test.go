@@ -0,0 +1,290 @@
+package loader_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"simulator/pkg/loader"
+	"simulator/pkg/synthetic"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resolved returns the schema filled in for a file of the given format.
+func resolved(t *testing.T, schema loader.Schema, format string) loader.Schema {
+	t.Helper()
+	schema, err := schema.Resolve("data." + format)
+	if err != nil {
+		t.Fatalf("resolving schema: %v", err)
+	}
+	return schema
+}
+
+// sameData fails the test unless got holds want's times and intensities.
+func sameData(t *testing.T, got, want []*loader.DataPoint) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("read %d data points, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].StartDate.Equal(want[i].StartDate) || got[i].CarbonIntensity != want[i].CarbonIntensity || got[i].MarginalIntensity != want[i].MarginalIntensity {
+			t.Fatalf("data point %d is %+v, want %+v", i, *got[i], *want[i])
+		}
+	}
+}
+
+func TestReadCSVSyntheticTrace(t *testing.T) {
+	trace := syntheticLoader(t, 2*time.Hour)
+	var written bytes.Buffer
+	if err := synthetic.WriteCSV(&written, trace.Data); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	data, marginal, err := resolved(t, loader.Schema{}, "csv").ReadCSV(&written)
+	if err != nil {
+		t.Fatalf("ReadCSV: %v", err)
+	}
+	if marginal {
+		t.Errorf("ReadCSV found marginal rates in a trace without them")
+	}
+	sameData(t, data, trace.Data)
+}
+
+func TestReadCSV(t *testing.T) {
+	first := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(5 * time.Minute)
+	tests := []struct {
+		name         string
+		schema       loader.Schema
+		input        string
+		want         []*loader.DataPoint
+		wantMarginal bool
+		wantErr      string
+	}{
+		{
+			name:  "provider layout",
+			input: "start_date,generated_rate_kg_per_mwh\n2023-01-01T00:00:00Z,250.5\n2023-01-01T00:05:00Z,260\n",
+			want:  []*loader.DataPoint{{StartDate: first, CarbonIntensity: 250.5}, {StartDate: second, CarbonIntensity: 260}},
+		},
+		{
+			name:  "header case, space and byte order mark",
+			input: "\ufeff Start_Date ,GENERATED_RATE_KG_PER_MWH\n2023-01-01T00:00:00Z,250.5\n",
+			want:  []*loader.DataPoint{{StartDate: first, CarbonIntensity: 250.5}},
+		},
+		{
+			name:  "blank intensities are skipped",
+			input: "start_date,generated_rate_kg_per_mwh\n2023-01-01T00:00:00Z,\n2023-01-01T00:05:00Z,260\n",
+			want:  []*loader.DataPoint{{StartDate: second, CarbonIntensity: 260}},
+		},
+		{
+			name:         "provider marginal column",
+			input:        "start_date,generated_rate_kg_per_mwh,marginal_rate_kg_per_mwh\n2023-01-01T00:00:00Z,250,-10.5\n",
+			want:         []*loader.DataPoint{{StartDate: first, CarbonIntensity: 250, MarginalIntensity: -10.5}},
+			wantMarginal: true,
+		},
+		{
+			name:   "custom columns and layout",
+			schema: loader.Schema{Time: "when", TimeLayout: "02/01/2006 15:04", Intensity: "co2", Unit: loader.GPerKWh},
+			input:  "co2,when\n120,01/01/2023 00:05\n",
+			want:   []*loader.DataPoint{{StartDate: second, CarbonIntensity: 120}},
+		},
+		{
+			name:    "missing time column",
+			input:   "time,generated_rate_kg_per_mwh\n2023-01-01T00:00:00Z,250\n",
+			wantErr: `no "start_date" column`,
+		},
+		{
+			name:    "missing intensity column",
+			input:   "start_date,intensity\n2023-01-01T00:00:00Z,250\n",
+			wantErr: `no "generated_rate_kg_per_mwh" column`,
+		},
+		{
+			name:    "missing requested marginal column",
+			schema:  loader.Schema{Time: "start_date", Intensity: "generated_rate_kg_per_mwh", Marginal: "marginal"},
+			input:   "start_date,generated_rate_kg_per_mwh\n2023-01-01T00:00:00Z,250\n",
+			wantErr: `no "marginal" column`,
+		},
+		{
+			name:    "short row",
+			input:   "start_date,generated_rate_kg_per_mwh\n2023-01-01T00:00:00Z\n",
+			wantErr: "line 2: missing columns",
+		},
+		{
+			name:    "invalid intensity",
+			input:   "start_date,generated_rate_kg_per_mwh\n2023-01-01T00:00:00Z,high\n",
+			wantErr: `line 2: invalid intensity "high"`,
+		},
+		{
+			name:    "invalid time",
+			input:   "start_date,generated_rate_kg_per_mwh\nyesterday,250\n",
+			wantErr: "line 2:",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, marginal, err := resolved(t, test.schema, "csv").ReadCSV(strings.NewReader(test.input))
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("ReadCSV error = %v, want one containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadCSV: %v", err)
+			}
+			if marginal != test.wantMarginal {
+				t.Errorf("ReadCSV marginal = %v, want %v", marginal, test.wantMarginal)
+			}
+			sameData(t, data, test.want)
+		})
+	}
+}
+
+func TestReadJSONSyntheticTrace(t *testing.T) {
+	trace := syntheticLoader(t, 2*time.Hour)
+	// The layout of the Singularity API, with intensities nested under data
+	records := make([]map[string]any, len(trace.Data))
+	for i, dataPoint := range trace.Data {
+		records[i] = map[string]any{
+			"start_date": dataPoint.StartDate.Format(time.RFC3339),
+			"data":       map[string]any{"generated_rate_kg_per_mwh": dataPoint.CarbonIntensity},
+		}
+	}
+	document, err := json.Marshal(map[string]any{"data": records})
+	if err != nil {
+		t.Fatalf("encoding trace: %v", err)
+	}
+	data, marginal, err := resolved(t, loader.Schema{}, "json").ReadJSON(bytes.NewReader(document))
+	if err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	if marginal {
+		t.Errorf("ReadJSON found marginal rates in a trace without them")
+	}
+	sameData(t, data, trace.Data)
+}
+
+func TestReadJSON(t *testing.T) {
+	first := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	tests := []struct {
+		name         string
+		schema       loader.Schema
+		input        string
+		want         []*loader.DataPoint
+		wantMarginal bool
+		wantErr      string
+	}{
+		{
+			name:  "provider layout",
+			input: `{"data": [{"start_date": "2023-01-01T00:00:00Z", "data": {"generated_rate_kg_per_mwh": 250.5}}]}`,
+			want:  []*loader.DataPoint{{StartDate: first, CarbonIntensity: 250.5}},
+		},
+		{
+			name:  "records without an intensity are skipped",
+			input: `{"data": [{"start_date": "2023-01-01T00:00:00Z", "data": {}}, {"start_date": "2023-01-01T01:00:00Z", "data": {"generated_rate_kg_per_mwh": 260}}]}`,
+			want:  []*loader.DataPoint{{StartDate: second, CarbonIntensity: 260}},
+		},
+		{
+			name:         "provider marginal field",
+			input:        `{"data": [{"start_date": "2023-01-01T00:00:00Z", "data": {"generated_rate_kg_per_mwh": 250, "marginal_rate_kg_per_mwh": 400}}]}`,
+			want:         []*loader.DataPoint{{StartDate: first, CarbonIntensity: 250, MarginalIntensity: 400}},
+			wantMarginal: true,
+		},
+		{
+			name:   "another provider",
+			schema: loader.Schema{Provider: "electricitymaps"},
+			input:  `{"history": [{"datetime": "2023-01-01T01:00:00.000Z", "carbonIntensity": 120}, {"datetime": "2023-01-01T00:00:00.000Z", "carbonIntensity": null}]}`,
+			want:   []*loader.DataPoint{{StartDate: second, CarbonIntensity: 120}},
+		},
+		{
+			name:   "top level array and string intensities",
+			schema: loader.Schema{Time: "t", Intensity: "v"},
+			input:  `[{"t": 1672531200, "v": "250"}]`,
+			want:   []*loader.DataPoint{{StartDate: first, CarbonIntensity: 250}},
+		},
+		{
+			name:    "no records at the path",
+			input:   `{"results": []}`,
+			wantErr: `no array of records at "data"`,
+		},
+		{
+			name:    "document is not an array",
+			schema:  loader.Schema{Time: "t", Intensity: "v"},
+			input:   `{"t": 0, "v": 1}`,
+			wantErr: "not an array of records",
+		},
+		{
+			name:    "missing time",
+			input:   `{"data": [{"data": {"generated_rate_kg_per_mwh": 250}}]}`,
+			wantErr: `record 1: missing "start_date"`,
+		},
+		{
+			name:    "invalid intensity",
+			input:   `{"data": [{"start_date": "2023-01-01T00:00:00Z", "data": {"generated_rate_kg_per_mwh": "high"}}]}`,
+			wantErr: `record 1: invalid intensity "high"`,
+		},
+		{
+			name:    "malformed document",
+			input:   `{"data": [`,
+			wantErr: "error parsing data",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, marginal, err := resolved(t, test.schema, "json").ReadJSON(strings.NewReader(test.input))
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("ReadJSON error = %v, want one containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadJSON: %v", err)
+			}
+			if marginal != test.wantMarginal {
+				t.Errorf("ReadJSON marginal = %v, want %v", marginal, test.wantMarginal)
+			}
+			sameData(t, data, test.want)
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2023, time.March, 12, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		layout  string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "RFC 3339", value: "2023-03-12T09:30:00Z", want: want},
+		{name: "RFC 3339 with offset", value: "2023-03-12T01:30:00-08:00", want: want},
+		{name: "fractional seconds", value: "2023-03-12T09:30:00.000Z", want: want},
+		{name: "date and time", value: "2023-03-12 09:30:00", want: want},
+		{name: "Unix seconds", value: strconv.FormatInt(want.Unix(), 10), want: want},
+		{name: "fractional Unix seconds", value: strconv.FormatInt(want.Unix(), 10) + ".5", want: want.Add(500 * time.Millisecond)},
+		{name: "custom layout", layout: "2006-01-02T15:04Z", value: "2023-03-12T09:30Z", want: want},
+		{name: "value not matching the layout", layout: "2006-01-02T15:04Z", value: "2023-03-12 09:30:00", wantErr: true},
+		{name: "unrecognised value", value: "March 12th", wantErr: true},
+		{name: "empty value", value: "", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := loader.Schema{TimeLayout: test.layout}.ParseTime(test.value)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTime(%q) = %v, want an error", test.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTime(%q): %v", test.value, err)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("ParseTime(%q) = %v, want %v", test.value, got, test.want)
+			}
+		})
+	}
+}
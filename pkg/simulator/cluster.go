@@ -0,0 +1,90 @@
+package simulator
+
+import (
+	"fmt"
+	"simulator/pkg/directory"
+)
+
+// Cluster models a finite pool of accelerators shared by every running job.
+// A capacity of zero leaves the cluster unbounded, which matches the behaviour
+// of the simulator before contention was modelled.
+type Cluster struct {
+	capacity int            // Total number of accelerators, 0 for unlimited
+	inUse    int            // Accelerators held by currently running jobs
+	slots    map[string]int // Per-model overrides of AIModelDefinition.Slots
+}
+
+func NewCluster(capacity int, slots map[string]int) *Cluster {
+	if slots == nil {
+		slots = make(map[string]int)
+	}
+	return &Cluster{
+		capacity: capacity,
+		inUse:    0,
+		slots:    slots,
+	}
+}
+
+// SlotsFor returns how many accelerators a job running the model occupies.
+// Overrides take precedence over the model definition, and a model without
+// any requirement occupies a single accelerator.
+func (c *Cluster) SlotsFor(model *directory.AIModelDefinition) int {
+	if slots, ok := c.slots[model.ModelName]; ok && slots > 0 {
+		return slots
+	}
+	if model.Slots > 0 {
+		return model.Slots
+	}
+	return 1
+}
+
+func (c *Cluster) Unbounded() bool {
+	return c.capacity <= 0
+}
+
+func (c *Cluster) CanAdmit(model *directory.AIModelDefinition) bool {
+	if c.Unbounded() {
+		return true
+	}
+	return c.inUse+c.SlotsFor(model) <= c.capacity
+}
+
+func (c *Cluster) Acquire(model *directory.AIModelDefinition) error {
+	if c.Unbounded() {
+		return nil
+	}
+	slots := c.SlotsFor(model)
+	if slots > c.capacity {
+		return fmt.Errorf("model %s requires %d slots but the cluster only has %d", model.ModelName, slots, c.capacity)
+	}
+	if c.inUse+slots > c.capacity {
+		return fmt.Errorf("cluster full: %d of %d slots in use", c.inUse, c.capacity)
+	}
+	c.inUse += slots
+	return nil
+}
+
+func (c *Cluster) Release(model *directory.AIModelDefinition) {
+	if c.Unbounded() {
+		return
+	}
+	c.inUse -= c.SlotsFor(model)
+	if c.inUse < 0 {
+		c.inUse = 0
+	}
+}
+
+func (c *Cluster) InUse() int {
+	return c.inUse
+}
+
+func (c *Cluster) Capacity() int {
+	return c.capacity
+}
+
+func (c *Cluster) String() string {
+	if c.Unbounded() {
+		return "Unbounded cluster"
+	}
+	return fmt.Sprintf("Cluster with %d/%d slots in use", c.inUse, c.capacity)
+}
@@ -0,0 +1,77 @@
+package simulator
+
+import (
+	"simulator/pkg/directory"
+	"testing"
+)
+
+func TestClusterAdmission(t *testing.T) {
+	small := &directory.AIModelDefinition{ModelName: "small"}
+	large := &directory.AIModelDefinition{ModelName: "large", Slots: 2}
+	tests := []struct {
+		name      string
+		capacity  int
+		slots     map[string]int
+		acquire   []*directory.AIModelDefinition // Acquired in order before next
+		next      *directory.AIModelDefinition
+		wantSlots int
+		wantAdmit bool
+		wantErr   bool
+		wantInUse int // After acquiring next
+	}{
+		{name: "unbounded", acquire: []*directory.AIModelDefinition{large, large, large}, next: large, wantSlots: 2, wantAdmit: true},
+		{name: "one slot by default", capacity: 2, next: small, wantSlots: 1, wantAdmit: true, wantInUse: 1},
+		{name: "slots from the model", capacity: 2, next: large, wantSlots: 2, wantAdmit: true, wantInUse: 2},
+		{name: "override takes precedence", capacity: 4, slots: map[string]int{"large": 3}, next: large, wantSlots: 3, wantAdmit: true, wantInUse: 3},
+		{name: "non-positive override is ignored", capacity: 4, slots: map[string]int{"small": 0}, next: small, wantSlots: 1, wantAdmit: true, wantInUse: 1},
+		{name: "fills to capacity", capacity: 3, acquire: []*directory.AIModelDefinition{large}, next: small, wantSlots: 1, wantAdmit: true, wantInUse: 3},
+		{name: "full", capacity: 3, acquire: []*directory.AIModelDefinition{large, small}, next: small, wantSlots: 1, wantErr: true, wantInUse: 3},
+		{name: "not enough room left", capacity: 3, acquire: []*directory.AIModelDefinition{large}, next: large, wantSlots: 2, wantErr: true, wantInUse: 2},
+		{name: "override above capacity", capacity: 2, slots: map[string]int{"small": 3}, next: small, wantSlots: 3, wantErr: true},
+		{name: "model above capacity", capacity: 1, next: large, wantSlots: 2, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cluster := NewCluster(test.capacity, test.slots)
+			for _, model := range test.acquire {
+				if err := cluster.Acquire(model); err != nil {
+					t.Fatalf("Acquire(%s): %v", model.ModelName, err)
+				}
+			}
+			if got := cluster.SlotsFor(test.next); got != test.wantSlots {
+				t.Errorf("SlotsFor(%s) = %d, want %d", test.next.ModelName, got, test.wantSlots)
+			}
+			if got := cluster.CanAdmit(test.next); got != test.wantAdmit {
+				t.Errorf("CanAdmit(%s) = %v, want %v", test.next.ModelName, got, test.wantAdmit)
+			}
+			if err := cluster.Acquire(test.next); (err != nil) != test.wantErr {
+				t.Errorf("Acquire(%s) = %v, want an error: %v", test.next.ModelName, err, test.wantErr)
+			}
+			if cluster.InUse() != test.wantInUse {
+				t.Errorf("%d slots in use, want %d", cluster.InUse(), test.wantInUse)
+			}
+		})
+	}
+}
+
+func TestClusterRelease(t *testing.T) {
+	large := &directory.AIModelDefinition{ModelName: "large", Slots: 2}
+	cluster := NewCluster(4, nil)
+	for range 2 {
+		if err := cluster.Acquire(large); err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+	}
+	if cluster.CanAdmit(large) {
+		t.Fatalf("full cluster admits another job")
+	}
+	cluster.Release(large)
+	if cluster.InUse() != 2 || !cluster.CanAdmit(large) {
+		t.Errorf("after a release %d slots are in use and admitting is %v, want 2 and true", cluster.InUse(), cluster.CanAdmit(large))
+	}
+	cluster.Release(large)
+	cluster.Release(large)
+	if cluster.InUse() != 0 {
+		t.Errorf("releasing more than was acquired leaves %d slots in use, want 0", cluster.InUse())
+	}
+}
@@ -0,0 +1,64 @@
+package simulator
+
+import (
+	"container/heap"
+	"math/rand"
+	"simulator/pkg/workload"
+	"slices"
+	"testing"
+	"time"
+)
+
+// The awaiting heap orders jobs by start and the running heap by end, so both
+// are driven through container/heap with random pushes and pops and checked
+// against a sorted copy of their contents.
+func TestHeapOrder(t *testing.T) {
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		heap interface {
+			heap.Interface
+			Peek() *workload.Job
+		}
+		key func(job *workload.Job) time.Time
+	}{
+		{name: "awaiting", heap: &AwaitingHeap{}, key: func(job *workload.Job) time.Time { return job.StartTime }},
+		{name: "running", heap: &RunningHeap{}, key: func(job *workload.Job) time.Time { return job.EndTime }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			held := make([]time.Time, 0)
+			for step := range 2000 {
+				if test.heap.Len() > 0 && r.Intn(3) == 0 {
+					slices.SortFunc(held, time.Time.Compare)
+					if peeked := test.key(test.heap.Peek()); !peeked.Equal(held[0]) {
+						t.Fatalf("step %d: Peek gave %v, want %v", step, peeked, held[0])
+					}
+					popped := test.key(heap.Pop(test.heap).(*workload.Job))
+					if !popped.Equal(held[0]) {
+						t.Fatalf("step %d: Pop gave %v, want %v", step, popped, held[0])
+					}
+					held = held[1:]
+				} else {
+					at := start.Add(time.Duration(r.Intn(500)) * time.Minute)
+					heap.Push(test.heap, &workload.Job{StartTime: at, EndTime: at})
+					held = append(held, at)
+				}
+				if test.heap.Len() != len(held) {
+					t.Fatalf("step %d: heap holds %d jobs, want %d", step, test.heap.Len(), len(held))
+				}
+			}
+			for test.heap.Len() > 0 {
+				slices.SortFunc(held, time.Time.Compare)
+				if popped := test.key(heap.Pop(test.heap).(*workload.Job)); !popped.Equal(held[0]) {
+					t.Fatalf("draining: Pop gave %v, want %v", popped, held[0])
+				}
+				held = held[1:]
+			}
+			if test.heap.Peek() != nil {
+				t.Errorf("Peek on an empty heap gave a job")
+			}
+		})
+	}
+}
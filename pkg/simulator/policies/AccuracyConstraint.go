@@ -0,0 +1,255 @@
+package policies
+
+import (
+	"fmt"
+	"simulator/pkg/directory"
+	"simulator/pkg/workload"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccuracyConstraint tracks the accuracy of the models jobs were served with
+// and decides whether serving one more job with a model keeps the mean at or
+// above the required accuracy. Jobs must be presented in arrival order.
+type AccuracyConstraint interface {
+	Allows(job *workload.Job, model *directory.AIModelDefinition) bool // Whether the constraint holds once the job is served by model
+	Record(job *workload.Job, model *directory.AIModelDefinition)      // Accounts for the job served by model
+	String() string
+}
+
+// GlobalAccuracy requires the mean accuracy over every job so far to meet the
+// target. It is the constraint the selection policies enforce by default.
+type GlobalAccuracy struct {
+	required      float64
+	totalAccuracy float64
+	processedJobs int
+}
+
+// WindowAccuracy requires the mean accuracy over a sliding window to meet the
+// target. The window holds either the last Jobs jobs or the jobs that arrived
+// within the last Span.
+type WindowAccuracy struct {
+	required float64
+	Jobs     int           // Window length in jobs, 0 when the window is a span of time
+	Span     time.Duration // Window length in time, 0 when the window counts jobs
+
+	arrivals   []time.Time
+	accuracies []float64
+	total      float64
+}
+
+// TenantAccuracy requires the mean accuracy over every job of each tenant to
+// meet the target. Jobs without a tenant form a tenant of their own.
+type TenantAccuracy struct {
+	required float64
+	tenants  map[string]*GlobalAccuracy
+}
+
+// JobAccuracy requires every job to be served by a model meeting the target.
+type JobAccuracy struct {
+	required float64
+}
+
+// AccuracyConstraints lists the constraint kinds ParseAccuracyConstraint accepts.
+var AccuracyConstraints = []string{"global", "window:<jobs>", "window:<duration>", "tenant", "job"}
+
+// ParseAccuracyConstraint returns the constraint described by spec with the
+// given target: global, window:N for the last N jobs, window:T for the jobs of
+// the last T (a duration such as 6h), tenant or job. An empty spec is global.
+func ParseAccuracyConstraint(spec string, required float64) (AccuracyConstraint, error) {
+	newConstraint, err := AccuracyConstraintFactory(spec, required)
+	if err != nil {
+		return nil, err
+	}
+	return newConstraint(), nil
+}
+
+// AccuracyConstraintFactory parses spec as ParseAccuracyConstraint does and
+// returns a function creating a constraint with no history on every call.
+func AccuracyConstraintFactory(spec string, required float64) (func() AccuracyConstraint, error) {
+	kind, parameter, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "", "global":
+		return func() AccuracyConstraint { return NewGlobalAccuracy(required) }, nil
+	case "window":
+		if jobs, err := strconv.Atoi(parameter); err == nil {
+			if jobs <= 0 {
+				return nil, fmt.Errorf("accuracy window must hold at least one job, got %d", jobs)
+			}
+			return func() AccuracyConstraint { return NewJobWindowAccuracy(required, jobs) }, nil
+		}
+		span, err := time.ParseDuration(parameter)
+		if err != nil || span <= 0 {
+			return nil, fmt.Errorf("invalid accuracy window %q, please give a number of jobs or a duration such as 6h", parameter)
+		}
+		return func() AccuracyConstraint { return NewTimeWindowAccuracy(required, span) }, nil
+	case "tenant":
+		return func() AccuracyConstraint { return NewTenantAccuracy(required) }, nil
+	case "job":
+		return func() AccuracyConstraint { return NewJobAccuracy(required) }, nil
+	default:
+		return nil, fmt.Errorf("invalid accuracy constraint %q, please choose %s", spec, strings.Join(AccuracyConstraints, ", "))
+	}
+}
+
+func NewGlobalAccuracy(required float64) *GlobalAccuracy {
+	return &GlobalAccuracy{
+		required: required,
+	}
+}
+
+func NewJobWindowAccuracy(required float64, jobs int) *WindowAccuracy {
+	return &WindowAccuracy{
+		required: required,
+		Jobs:     jobs,
+	}
+}
+
+func NewTimeWindowAccuracy(required float64, span time.Duration) *WindowAccuracy {
+	return &WindowAccuracy{
+		required: required,
+		Span:     span,
+	}
+}
+
+func NewTenantAccuracy(required float64) *TenantAccuracy {
+	return &TenantAccuracy{
+		required: required,
+		tenants:  make(map[string]*GlobalAccuracy),
+	}
+}
+
+func NewJobAccuracy(required float64) *JobAccuracy {
+	return &JobAccuracy{
+		required: required,
+	}
+}
+
+func (g *GlobalAccuracy) Allows(job *workload.Job, model *directory.AIModelDefinition) bool {
+	newAccuracy := (g.totalAccuracy + model.Accuracy) / float64(g.processedJobs+1)
+	return newAccuracy >= g.required
+}
+
+func (g *GlobalAccuracy) Record(job *workload.Job, model *directory.AIModelDefinition) {
+	g.totalAccuracy += model.Accuracy
+	g.processedJobs++
+}
+
+func (g *GlobalAccuracy) String() string {
+	return "global"
+}
+
+// arrival returns when the job was decided on, the time windows slide with.
+func arrival(job *workload.Job) time.Time {
+	if job.ArrivalTime.IsZero() {
+		return job.StartTime
+	}
+	return job.ArrivalTime
+}
+
+// evict drops the jobs that leave the window once a job arrives at now.
+func (w *WindowAccuracy) evict(now time.Time) {
+	drop := 0
+	if w.Jobs > 0 {
+		drop = max(len(w.accuracies)-(w.Jobs-1), 0)
+	} else {
+		for drop < len(w.arrivals) && !w.arrivals[drop].After(now.Add(-w.Span)) {
+			drop++
+		}
+	}
+	for _, accuracy := range w.accuracies[:drop] {
+		w.total -= accuracy
+	}
+	w.arrivals = w.arrivals[drop:]
+	w.accuracies = w.accuracies[drop:]
+}
+
+func (w *WindowAccuracy) Allows(job *workload.Job, model *directory.AIModelDefinition) bool {
+	w.evict(arrival(job))
+	newAccuracy := (w.total + model.Accuracy) / float64(len(w.accuracies)+1)
+	return newAccuracy >= w.required
+}
+
+func (w *WindowAccuracy) Record(job *workload.Job, model *directory.AIModelDefinition) {
+	w.evict(arrival(job))
+	w.arrivals = append(w.arrivals, arrival(job))
+	w.accuracies = append(w.accuracies, model.Accuracy)
+	w.total += model.Accuracy
+}
+
+func (w *WindowAccuracy) String() string {
+	if w.Jobs > 0 {
+		return fmt.Sprintf("window:%d", w.Jobs)
+	}
+	// Drop the zero minutes and seconds so 6h reads as it was written
+	span := w.Span.String()
+	if strings.HasSuffix(span, "m0s") {
+		span = strings.TrimSuffix(span, "0s")
+	}
+	if strings.HasSuffix(span, "h0m") {
+		span = strings.TrimSuffix(span, "0m")
+	}
+	return "window:" + span
+}
+
+func (t *TenantAccuracy) tenant(job *workload.Job) *GlobalAccuracy {
+	constraint, ok := t.tenants[job.Tenant]
+	if !ok {
+		constraint = NewGlobalAccuracy(t.required)
+		t.tenants[job.Tenant] = constraint
+	}
+	return constraint
+}
+
+func (t *TenantAccuracy) Allows(job *workload.Job, model *directory.AIModelDefinition) bool {
+	return t.tenant(job).Allows(job, model)
+}
+
+func (t *TenantAccuracy) Record(job *workload.Job, model *directory.AIModelDefinition) {
+	t.tenant(job).Record(job, model)
+}
+
+func (t *TenantAccuracy) String() string {
+	return "tenant"
+}
+
+func (j *JobAccuracy) Allows(job *workload.Job, model *directory.AIModelDefinition) bool {
+	return model.Accuracy >= j.required
+}
+
+func (j *JobAccuracy) Record(job *workload.Job, model *directory.AIModelDefinition) {}
+
+func (j *JobAccuracy) String() string {
+	return "job"
+}
+
+// allowedModels returns the models, in the directory's fixed order, that meet
+// both the constraint and the job's own minimum. When the constraint cannot be
+// met it falls back to the most accurate model meeting the job's minimum and
+// notes the shortfall on the job.
+func allowedModels(modelDirectory *directory.Directory, constraint AccuracyConstraint, job *workload.Job) ([]*directory.AIModelDefinition, error) {
+	models := modelDirectory.GetModels()
+	allowed := make([]*directory.AIModelDefinition, 0, len(models))
+	var mostAccurate *directory.AIModelDefinition
+	for _, name := range modelDirectory.GetModelNames() {
+		model := models[name]
+		if model.Accuracy < job.RequiredAccuracy {
+			continue
+		}
+		if mostAccurate == nil || model.Accuracy > mostAccurate.Accuracy {
+			mostAccurate = &model
+		}
+		if constraint.Allows(job, &model) {
+			allowed = append(allowed, &model)
+		}
+	}
+	if len(allowed) > 0 {
+		return allowed, nil
+	}
+	if mostAccurate == nil {
+		return nil, fmt.Errorf("no model found that meets the job's own minimum accuracy of %f", job.RequiredAccuracy)
+	}
+	job.Note("accuracy_constraint", "unmet")
+	return []*directory.AIModelDefinition{mostAccurate}, nil
+}
@@ -0,0 +1,147 @@
+package policies
+
+import (
+	"simulator/pkg/workload"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseAccuracyConstraint(t *testing.T) {
+	tests := []struct {
+		spec     string
+		want     string // String of the parsed constraint, empty when parsing fails
+		wantJobs int
+		wantSpan time.Duration
+	}{
+		{spec: "", want: "global"},
+		{spec: "global", want: "global"},
+		{spec: "window:10", want: "window:10", wantJobs: 10},
+		{spec: "window:1", want: "window:1", wantJobs: 1},
+		{spec: "window:6h", want: "window:6h", wantSpan: 6 * time.Hour},
+		{spec: "window:90m", want: "window:1h30m", wantSpan: 90 * time.Minute},
+		{spec: "window:45s", want: "window:45s", wantSpan: 45 * time.Second},
+		{spec: "tenant", want: "tenant"},
+		{spec: "job", want: "job"},
+		{spec: "window:0"},
+		{spec: "window:-3"},
+		{spec: "window:-1h"},
+		{spec: "window:"},
+		{spec: "window:soon"},
+		{spec: "regional"},
+		{spec: "Global"},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			constraint, err := ParseAccuracyConstraint(test.spec, 0.7)
+			if test.want == "" {
+				if err == nil {
+					t.Fatalf("ParseAccuracyConstraint(%q) = %v, want an error", test.spec, constraint)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAccuracyConstraint(%q): %v", test.spec, err)
+			}
+			if got := constraint.String(); got != test.want {
+				t.Errorf("ParseAccuracyConstraint(%q) = %s, want %s", test.spec, got, test.want)
+			}
+			if window, ok := constraint.(*WindowAccuracy); ok {
+				if window.Jobs != test.wantJobs || window.Span != test.wantSpan {
+					t.Errorf("window of %d jobs and %v, want %d jobs and %v", window.Jobs, window.Span, test.wantJobs, test.wantSpan)
+				}
+			} else if test.wantJobs != 0 || test.wantSpan != 0 {
+				t.Errorf("ParseAccuracyConstraint(%q) gave a %T, want a window", test.spec, constraint)
+			}
+		})
+	}
+}
+
+func TestWindowAccuracyEvict(t *testing.T) {
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	type recorded struct {
+		arrival  time.Duration // After start
+		accuracy float64
+	}
+	tests := []struct {
+		name     string
+		window   *WindowAccuracy
+		recorded []recorded
+		now      time.Duration
+		want     []float64 // Accuracies left in the window
+	}{
+		{
+			name:     "job window below its length",
+			window:   NewJobWindowAccuracy(0.7, 5),
+			recorded: []recorded{{0, 0.5}, {time.Minute, 0.9}},
+			now:      2 * time.Minute,
+			want:     []float64{0.5, 0.9},
+		},
+		{
+			name:     "job window makes room for the arrival",
+			window:   NewJobWindowAccuracy(0.7, 3),
+			recorded: []recorded{{0, 0.5}, {time.Minute, 0.75}, {2 * time.Minute, 0.9}, {3 * time.Minute, 0.5}, {4 * time.Minute, 0.75}},
+			now:      5 * time.Minute,
+			want:     []float64{0.5, 0.75},
+		},
+		{
+			name:     "window of one job",
+			window:   NewJobWindowAccuracy(0.7, 1),
+			recorded: []recorded{{0, 0.5}, {time.Minute, 0.9}},
+			now:      2 * time.Minute,
+			want:     []float64{},
+		},
+		{
+			name:     "time window keeps recent arrivals",
+			window:   NewTimeWindowAccuracy(0.7, time.Hour),
+			recorded: []recorded{{0, 0.5}, {20 * time.Minute, 0.75}, {50 * time.Minute, 0.9}, {70 * time.Minute, 0.5}},
+			now:      80 * time.Minute,
+			want:     []float64{0.9, 0.5},
+		},
+		{
+			name:     "time window drops arrivals exactly one span old",
+			window:   NewTimeWindowAccuracy(0.7, time.Hour),
+			recorded: []recorded{{0, 0.5}, {30 * time.Minute, 0.9}},
+			now:      time.Hour,
+			want:     []float64{0.9},
+		},
+		{
+			name:     "time window after a long lull",
+			window:   NewTimeWindowAccuracy(0.7, time.Hour),
+			recorded: []recorded{{0, 0.5}, {10 * time.Minute, 0.9}},
+			now:      24 * time.Hour,
+			want:     []float64{},
+		},
+		{
+			name:     "time window with simultaneous arrivals",
+			window:   NewTimeWindowAccuracy(0.7, 30*time.Minute),
+			recorded: []recorded{{0, 0.5}, {0, 0.9}, {10 * time.Minute, 0.75}},
+			now:      35 * time.Minute,
+			want:     []float64{0.75},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, entry := range test.recorded {
+				job := &workload.Job{ArrivalTime: start.Add(entry.arrival)}
+				model := smallModel
+				model.Accuracy = entry.accuracy
+				test.window.Record(job, &model)
+			}
+			test.window.evict(start.Add(test.now))
+			if !slices.Equal(test.window.accuracies, test.want) {
+				t.Errorf("window holds %v, want %v", test.window.accuracies, test.want)
+			}
+			if len(test.window.arrivals) != len(test.window.accuracies) {
+				t.Errorf("window holds %d arrivals for %d accuracies", len(test.window.arrivals), len(test.window.accuracies))
+			}
+			total := 0.0
+			for _, accuracy := range test.want {
+				total += accuracy
+			}
+			if !closeTo(test.window.total, total) {
+				t.Errorf("window total %f, want %f", test.window.total, total)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package policies
+
+import (
+	"math"
+	"simulator/pkg/directory"
+	"simulator/pkg/loader"
+	"simulator/pkg/synthetic"
+	"slices"
+	"testing"
+	"time"
+)
+
+var (
+	smallModel  = directory.AIModelDefinition{ModelName: "small", MeanRunTime: 120, StdDevRunTime: 20, EnergyUsage: 5, Accuracy: 0.5}
+	mediumModel = directory.AIModelDefinition{ModelName: "medium", MeanRunTime: 240, StdDevRunTime: 40, EnergyUsage: 10, Accuracy: 0.75}
+	largeModel  = directory.AIModelDefinition{ModelName: "large", MeanRunTime: 480, StdDevRunTime: 80, EnergyUsage: 20, Accuracy: 0.9}
+)
+
+// syntheticLoader returns two days of synthetic data every five minutes with
+// the entries at the given indices left out.
+func syntheticLoader(t *testing.T, removed ...int) *loader.Loader {
+	t.Helper()
+	model := synthetic.Default()
+	model.Duration = 48 * time.Hour
+	trace, err := synthetic.NewLoader(model, 1)
+	if err != nil {
+		t.Fatalf("generating trace: %v", err)
+	}
+	if len(removed) == 0 {
+		return trace
+	}
+	data := make([]*loader.DataPoint, 0, trace.NumEntries())
+	for i, dataPoint := range trace.Data {
+		if !slices.Contains(removed, i) {
+			data = append(data, dataPoint)
+		}
+	}
+	return loader.NewLoaderFromData("synthetic trace with gaps", data, trace.Location())
+}
+
+// loopCarbonCalculate is CarbonCalculate as it was before the loader kept an
+// integral index, stepping through every entry the run overlaps.
+func loopCarbonCalculate(loader *loader.Loader, start time.Time, end time.Time, model *directory.AIModelDefinition) float64 {
+	totalCarbon := 0.0
+	currTime := start
+	for currTime.Before(end) && currTime.Before(loader.EndDate()) {
+		carbonIdx, err := loader.GetIndexByDate(currTime)
+		if err != nil {
+			panic(err)
+		}
+		var nextTime time.Time
+		if carbonIdx < loader.NumEntries()-1 && loader.Data[carbonIdx+1].StartDate.Before(end) {
+			nextTime = loader.Data[carbonIdx+1].StartDate
+		} else {
+			nextTime = end
+		}
+		timeDelta := nextTime.Sub(currTime).Seconds()
+		totalCarbon += timeDelta * model.EnergyUsage * 3.6e-9 * 1e3 * loader.Data[carbonIdx].CarbonIntensity
+		currTime = nextTime
+	}
+	return totalCarbon
+}
+
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) <= 1e-9*max(1, math.Abs(want))
+}
+
+func TestCarbonCalculateMatchesLoop(t *testing.T) {
+	tests := []struct {
+		name    string
+		removed []int
+		start   time.Duration // From the start of the data
+		length  time.Duration
+	}{
+		{name: "within one entry", start: 61 * time.Second, length: 2 * time.Minute},
+		{name: "aligned to entries", start: time.Hour, length: time.Hour},
+		{name: "unaligned across entries", start: 7*time.Minute + 13*time.Second, length: 3*time.Hour + 41*time.Second},
+		{name: "across a gap", removed: []int{20, 21, 22, 23}, start: 90 * time.Minute, length: 45 * time.Minute},
+		{name: "starting in a gap", removed: []int{20, 21, 22, 23}, start: 102 * time.Minute, length: 30 * time.Minute},
+		{name: "whole trace", length: 48 * time.Hour},
+		{name: "past the end of the data", start: 47 * time.Hour, length: 3 * time.Hour},
+		{name: "starting after the data", start: 49 * time.Hour, length: time.Hour},
+		{name: "empty run", start: time.Hour},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dataLoader := syntheticLoader(t, test.removed...)
+			start := dataLoader.StartDate().Add(test.start)
+			end := start.Add(test.length)
+			for _, model := range []*directory.AIModelDefinition{&smallModel, &largeModel} {
+				want := loopCarbonCalculate(dataLoader, start, end, model)
+				if got := CarbonCalculate(dataLoader, start, end, model); !closeTo(got, want) {
+					t.Errorf("CarbonCalculate with %s = %f, want %f", model.ModelName, got, want)
+				}
+				intensity, err := dataLoader.Integral(start, end)
+				if err != nil {
+					t.Fatalf("Integral: %v", err)
+				}
+				if got := modelCarbon(intensity, model); !closeTo(got, want) {
+					t.Errorf("Integral with %s gives %f gCO2, want %f", model.ModelName, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWindowIntegralMatchesLoop(t *testing.T) {
+	dataLoader := syntheticLoader(t, 40, 41, 300, 301, 302)
+	tests := []struct {
+		name   string
+		length time.Duration
+		starts []time.Duration // Successive positions of the window
+	}{
+		{name: "sliding forward by entries", length: 2 * time.Hour, starts: []time.Duration{0, 5 * time.Minute, 10 * time.Minute, 3 * time.Hour}},
+		{name: "sliding forward unaligned", length: 37 * time.Minute, starts: []time.Duration{time.Minute, 3*time.Hour + 7*time.Second, 24*time.Hour + 59*time.Second}},
+		{name: "moving back", length: time.Hour, starts: []time.Duration{10 * time.Hour, 2 * time.Hour, 2*time.Hour + 30*time.Second}},
+		{name: "across gaps", length: 90 * time.Minute, starts: []time.Duration{3 * time.Hour, 24 * time.Hour, 25 * time.Hour}},
+		{name: "running off the data", length: 4 * time.Hour, starts: []time.Duration{46 * time.Hour, 47 * time.Hour, 50 * time.Hour}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			window := dataLoader.NewWindow(test.length)
+			for _, offset := range test.starts {
+				start := dataLoader.StartDate().Add(offset)
+				intensity, err := window.Integral(start)
+				if err != nil {
+					t.Fatalf("Integral(%v): %v", start, err)
+				}
+				want := loopCarbonCalculate(dataLoader, start, start.Add(test.length), &mediumModel)
+				if got := modelCarbon(intensity, &mediumModel); !closeTo(got, want) {
+					t.Errorf("window at %v gives %f gCO2, want %f", offset, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegralBeforeData(t *testing.T) {
+	dataLoader := syntheticLoader(t)
+	start := dataLoader.StartDate().Add(-time.Hour)
+	if _, err := dataLoader.Integral(start, start.Add(2*time.Hour)); err == nil {
+		t.Errorf("Integral from before the data succeeded, want an error")
+	}
+	if _, err := dataLoader.NewWindow(2 * time.Hour).Integral(start); err == nil {
+		t.Errorf("Window.Integral from before the data succeeded, want an error")
+	}
+}
@@ -2,28 +2,30 @@ package policies
 
 import (
 	"fmt"
-	"log"
-	"math/rand"
 	"simulator/pkg/directory"
+	"simulator/pkg/environment"
 	"simulator/pkg/workload"
 	"time"
 )
 
 type FIFO struct {
+	env     *environment.Environment
 	aiModel *directory.AIModelDefinition
 }
 
-func NewFIFO(aiModel *directory.AIModelDefinition) *FIFO {
+func NewFIFO(env *environment.Environment, aiModel *directory.AIModelDefinition) *FIFO {
 	return &FIFO{
+		env:     env,
 		aiModel: aiModel,
 	}
 }
 
 func (f *FIFO) HandleIncoming(job *workload.Job) error {
 	job.Model = f.aiModel
-	_ = FIFOCarbonEstimate(job, f.aiModel)
+	carbonPredict := FIFOCarbonEstimate(f.env, job, f.aiModel)
+	job.Note("predicted_carbon_g", formatCarbon(carbonPredict))
 	// Generate the duration of the job
-	duration := max(f.aiModel.MeanRunTime+f.aiModel.StdDevRunTime*rand.NormFloat64(), 0)
+	duration := max(f.aiModel.MeanRunTime+f.aiModel.StdDevRunTime*f.env.Rand.NormFloat64(), 0)
 	job.EndTime = job.StartTime.Add(time.Duration(duration) * time.Second)
 	return nil
 }
@@ -40,9 +42,19 @@ func (f *FIFO) String() string {
 	return fmt.Sprintf("FIFO with %s", f.aiModel.ModelName)
 }
 
-func FIFOCarbonEstimate(job *workload.Job, aiModel *directory.AIModelDefinition) float64 {
-	expectedEnd := job.StartTime.Add(time.Duration(aiModel.MeanRunTime) * time.Second)
-	totalCarbon := CarbonCalculate(job.StartTime, expectedEnd, aiModel)
-	log.Printf("[FIFO PREDICT] For start time %s, estimated end %s, and model %s, total carbon is predicted %f gCO2", job.StartTime.Format(time.ANSIC), expectedEnd.Format(time.ANSIC), aiModel.ModelName, totalCarbon)
+func FIFOCarbonEstimate(env *environment.Environment, job *workload.Job, aiModel *directory.AIModelDefinition) float64 {
+	expectedEnd, totalCarbon := fifoCarbonPredict(env, job, aiModel)
+	logFIFOPrediction(env, job, aiModel, expectedEnd, totalCarbon)
 	return totalCarbon
 }
+
+// fifoCarbonPredict estimates the carbon of running the job immediately for
+// its mean runtime. It does not log so it is safe to call concurrently.
+func fifoCarbonPredict(env *environment.Environment, job *workload.Job, aiModel *directory.AIModelDefinition) (time.Time, float64) {
+	expectedEnd := job.StartTime.Add(time.Duration(aiModel.MeanRunTime) * time.Second)
+	return expectedEnd, CarbonCalculate(forecastLoader(env, job.Region, job.StartTime, expectedEnd), job.StartTime, expectedEnd, aiModel)
+}
+
+func logFIFOPrediction(env *environment.Environment, job *workload.Job, aiModel *directory.AIModelDefinition, expectedEnd time.Time, totalCarbon float64) {
+	env.Logger.Printf("[FIFO PREDICT] For start time %s, estimated end %s, and model %s, total carbon is predicted %f gCO2", job.StartTime.Format(time.ANSIC), expectedEnd.Format(time.ANSIC), aiModel.ModelName, totalCarbon)
+}
@@ -3,18 +3,19 @@ package policies
 import (
 	"fmt"
 	"math"
-	"math/rand"
 	"simulator/pkg/directory"
+	"simulator/pkg/environment"
 	"simulator/pkg/workload"
+	"strings"
 	"sync"
 	"time"
 )
 
 type HybridSelection struct {
-	requiredAccuracy  float64
-	safeguardSD       float64
-	currTotalAccuracy float64
-	processedJobs     int
+	env              *environment.Environment
+	requiredAccuracy float64
+	safeguardSD      float64
+	constraint       AccuracyConstraint
 }
 
 type HybridSelectionEstimate struct {
@@ -23,55 +24,56 @@ type HybridSelectionEstimate struct {
 	Model          *directory.AIModelDefinition
 }
 
-func NewHybridSelection(requiredAccuracy float64, safeguardSD float64) *HybridSelection {
+// NewHybridSelection returns the policy enforcing requiredAccuracy through the
+// constraint, or as a global mean when the constraint is nil.
+func NewHybridSelection(env *environment.Environment, requiredAccuracy float64, safeguardSD float64, constraint AccuracyConstraint) *HybridSelection {
+	if constraint == nil {
+		constraint = NewGlobalAccuracy(requiredAccuracy)
+	}
 	return &HybridSelection{
-		requiredAccuracy:  requiredAccuracy,
-		safeguardSD:       safeguardSD,
-		currTotalAccuracy: 0,
-		processedJobs:     0,
+		env:              env,
+		requiredAccuracy: requiredAccuracy,
+		safeguardSD:      safeguardSD,
+		constraint:       constraint,
 	}
 }
 
 func (h *HybridSelection) HandleIncoming(job *workload.Job) error {
-	modelDirectory := directory.FetchDirectory()
+	modelDirectory := h.env.Directory
 	if modelDirectory == nil {
 		return fmt.Errorf("model directory not initialized")
 	}
-	models := modelDirectory.GetModels()
+	// Models are visited in a fixed order so ties resolve identically across runs
+	allowed, err := allowedModels(modelDirectory, h.constraint, job)
+	if err != nil {
+		return err
+	}
 	var selectedModel *directory.AIModelDefinition
-	arrayLen := 0
 	var wg sync.WaitGroup
-	for _, model := range models {
-		newAccuracy := (h.currTotalAccuracy + model.Accuracy) / float64(h.processedJobs+1)
-		if newAccuracy >= h.requiredAccuracy {
-			arrayLen++
-		}
+	wg.Add(len(allowed))
+	array := make([]HybridSelectionEstimate, len(allowed))
+	errs := make([]error, len(allowed))
+	for i, model := range allowed {
+		go func(index int, model *directory.AIModelDefinition) {
+			defer wg.Done()
+			bestTime, carbonEstimate, err := TemporalCarbonEstimate(h.env, job, model, h.safeguardSD)
+			if err != nil {
+				errs[index] = fmt.Errorf("error estimating carbon with %s: %w", model.ModelName, err)
+				return
+			}
+			array[index] = HybridSelectionEstimate{
+				BestStartTime:  bestTime,
+				CarbonEstimate: carbonEstimate,
+				Model:          model,
+			}
+		}(i, model)
 	}
-	if arrayLen == 0 {
-		return fmt.Errorf("no model found that meets the required accuracy, there is likely no model with an accuracy >= to the required accuracy")
-	}
-	wg.Add(arrayLen)
-	array := make([]HybridSelectionEstimate, arrayLen)
-	i := 0
-	for _, model := range models {
-		newAccuracy := (h.currTotalAccuracy + model.Accuracy) / float64(h.processedJobs+1)
-		if newAccuracy >= h.requiredAccuracy {
-			go func(index int, model *directory.AIModelDefinition) {
-				defer wg.Done()
-				bestTime, carbonEstimate, err := TemporalCarbonEstimate(job, model, h.safeguardSD)
-				if err != nil {
-					panic(err)
-				}
-				array[index] = HybridSelectionEstimate{
-					BestStartTime:  bestTime,
-					CarbonEstimate: carbonEstimate,
-					Model:          model,
-				}
-			}(i, &model)
-			i += 1
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
 		}
 	}
-	wg.Wait()
 	bestStartTime := job.StartTime
 	bestCarbon := math.MaxFloat64
 	bestAccuracy := math.MaxFloat64
@@ -84,15 +86,23 @@ func (h *HybridSelection) HandleIncoming(job *workload.Job) error {
 		}
 	}
 	job.Model = selectedModel
+	job.Note("predicted_carbon_g", formatCarbon(bestCarbon))
+	job.Note("candidates", hybridCandidateNames(array))
+	job.Note("shift", bestStartTime.Sub(job.StartTime).String())
 	job.StartTime = bestStartTime
-	duration := max(selectedModel.MeanRunTime+selectedModel.StdDevRunTime*rand.NormFloat64(), 0)
+	duration := max(selectedModel.MeanRunTime+selectedModel.StdDevRunTime*h.env.Rand.NormFloat64(), 0)
 	job.EndTime = job.StartTime.Add(time.Duration(duration) * time.Second)
 
-	h.currTotalAccuracy += selectedModel.Accuracy
-	h.processedJobs++
+	h.constraint.Record(job, selectedModel)
 	return nil
 }
 
+// Replan looks for a cheaper later start for the chosen model once the planned
+// start arrives.
+func (h HybridSelection) Replan(job *workload.Job) (bool, error) {
+	return replanJob(h.env, job, h.safeguardSD)
+}
+
 func (h HybridSelection) HandleQueued(job *workload.Job) error {
 	return nil
 }
@@ -102,5 +112,13 @@ func (h HybridSelection) HandleRunning(job *workload.Job) error {
 }
 
 func (h HybridSelection) String() string {
-	return fmt.Sprintf("HybridSelection with required accuracy: %f", h.requiredAccuracy)
+	return fmt.Sprintf("HybridSelection with required accuracy: %f", h.requiredAccuracy) + constraintSuffix(h.constraint)
+}
+
+func hybridCandidateNames(estimates []HybridSelectionEstimate) string {
+	names := make([]string, len(estimates))
+	for i, estimate := range estimates {
+		names[i] = estimate.Model.ModelName
+	}
+	return strings.Join(names, ",")
 }
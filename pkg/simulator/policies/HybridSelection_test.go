@@ -0,0 +1,78 @@
+package policies
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"simulator/pkg/directory"
+	"simulator/pkg/environment"
+	"simulator/pkg/loader"
+	"simulator/pkg/workload"
+	"testing"
+	"time"
+)
+
+// testDirectory returns a directory of the small, medium and large models.
+func testDirectory(t *testing.T) *directory.Directory {
+	t.Helper()
+	models := make(map[string]directory.AIModelDefinition)
+	for _, model := range []directory.AIModelDefinition{smallModel, mediumModel, largeModel} {
+		models[model.ModelName] = model
+	}
+	data, err := json.Marshal(models)
+	if err != nil {
+		t.Fatalf("encoding models: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "models.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("writing models: %v", err)
+	}
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	return directory.NewDirectory(path)
+}
+
+func TestHybridSelectionHandleIncoming(t *testing.T) {
+	dataLoader := syntheticLoader(t)
+	tests := []struct {
+		name     string
+		loader   *loader.Loader
+		required float64
+		wantErr  bool
+	}{
+		{name: "cheapest model meets the target", loader: dataLoader, required: 0.5},
+		{name: "target rules out the small model", loader: dataLoader, required: 0.7},
+		{name: "estimates fail", loader: loader.NewLoaderFromData("empty", nil, time.UTC), required: 0.5, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := &environment.Environment{
+				Loader:    test.loader,
+				Directory: testDirectory(t),
+				Logger:    log.New(io.Discard, "", 0),
+				Rand:      rand.New(rand.NewSource(1)),
+			}
+			arrival := dataLoader.StartDate().Add(time.Hour)
+			job := &workload.Job{ArrivalTime: arrival, StartTime: arrival, DueTime: arrival.Add(6 * time.Hour)}
+			err := NewHybridSelection(env, test.required, 1, nil).HandleIncoming(job)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("HandleIncoming succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HandleIncoming: %v", err)
+			}
+			if job.Model == nil || job.Model.Accuracy < test.required {
+				t.Fatalf("chose %v for a target of %f", job.Model, test.required)
+			}
+			if job.StartTime.Before(arrival) || job.StartTime.After(job.DueTime) {
+				t.Errorf("start %v outside %v to %v", job.StartTime, arrival, job.DueTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package policies
+
+import (
+	"fmt"
+	"simulator/pkg/directory"
+	"simulator/pkg/environment"
+	"simulator/pkg/workload"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Interruptible runs each job in the cleanest intervals before its deadline,
+// suspending it in between. Every resumed segment pays the checkpoint
+// overhead, so the policy falls back to a single contiguous run whenever that
+// is predicted to be cheaper.
+type Interruptible struct {
+	env                *environment.Environment
+	aiModel            *directory.AIModelDefinition
+	safeguardSD        float64
+	checkpointOverhead time.Duration
+}
+
+func NewInterruptible(env *environment.Environment, aiModel *directory.AIModelDefinition, safeguardSD float64, checkpointOverhead time.Duration) *Interruptible {
+	return &Interruptible{
+		env:                env,
+		aiModel:            aiModel,
+		safeguardSD:        safeguardSD,
+		checkpointOverhead: checkpointOverhead,
+	}
+}
+
+func (i *Interruptible) HandleIncoming(job *workload.Job) error {
+	job.Model = i.aiModel
+	windows, carbonPredict, err := InterruptibleCarbonEstimate(i.env, job, i.aiModel, i.safeguardSD, i.checkpointOverhead)
+	if err != nil {
+		return err
+	}
+	i.env.Logger.Printf("[INTERRUPTIBLE PREDICT] For start time %s, %d segments, and model %s, total carbon is predicted %f gCO2", windows[0].Start.Format(time.ANSIC), len(windows), i.aiModel.ModelName, carbonPredict)
+	job.Note("predicted_carbon_g", formatCarbon(carbonPredict))
+	job.Note("shift", windows[0].Start.Sub(job.StartTime).String())
+	job.Note("planned_segments", strconv.Itoa(len(windows)))
+	duration := time.Duration(max(i.aiModel.MeanRunTime+i.aiModel.StdDevRunTime*i.env.Rand.NormFloat64(), 0)) * time.Second
+	// The first segment runs to the end of its window unless the work is done sooner
+	run := duration
+	if len(windows) > 1 {
+		run = min(duration, windows[0].End.Sub(windows[0].Start))
+	}
+	job.StartTime = windows[0].Start
+	job.EndTime = job.StartTime.Add(run)
+	job.Remaining = duration - run
+	job.Plan = windows[1:]
+	job.CheckpointOverhead = i.checkpointOverhead
+	return nil
+}
+
+func (i *Interruptible) HandleQueued(job *workload.Job) error {
+	return nil
+}
+
+func (i *Interruptible) HandleRunning(job *workload.Job) error {
+	return nil
+}
+
+func (i *Interruptible) String() string {
+	return fmt.Sprintf("Interruptible with %s, Standard Deviation Guard: %f and Checkpoint Overhead: %v", i.aiModel.ModelName, i.safeguardSD, i.checkpointOverhead)
+}
+
+// InterruptibleCarbonEstimate returns the windows the job should run in and
+// their predicted carbon. The cleanest data intervals between arrival and the
+// deadline are taken until they hold the expected runtime plus the overhead of
+// every resumed segment. When that is impossible, or no cheaper than the best
+// contiguous run, a single window is returned instead.
+func InterruptibleCarbonEstimate(env *environment.Environment, job *workload.Job, aiModel *directory.AIModelDefinition, safeguardSD float64, checkpointOverhead time.Duration) ([]workload.Segment, float64, error) {
+	expected := time.Duration(aiModel.MeanRunTime)*time.Second + time.Duration(aiModel.StdDevRunTime*safeguardSD)*time.Second
+	bestTime, contiguousCarbon, err := TemporalCarbonEstimate(env, job, aiModel, safeguardSD)
+	if err != nil {
+		return nil, 0, err
+	}
+	contiguous := []workload.Segment{{Start: bestTime, End: bestTime.Add(expected)}}
+	if expected <= 0 || !job.DueTime.After(job.StartTime) {
+		return contiguous, contiguousCarbon, nil
+	}
+
+	loader := forecastLoader(env, job.Region, job.StartTime, job.DueTime)
+	first, err := loader.GetIndexByDate(job.StartTime)
+	if err != nil {
+		return contiguous, contiguousCarbon, nil
+	}
+	// Intervals of the data clipped to the time between arrival and deadline,
+	// cleanest first
+	type interval struct {
+		segment   workload.Segment
+		intensity float64
+	}
+	intervals := make([]interval, 0)
+	for idx := first; idx < loader.NumEntries(); idx++ {
+		start := loader.Data[idx].StartDate
+		if !start.Before(job.DueTime) {
+			break
+		}
+		end := loader.EndDate()
+		if idx < loader.NumEntries()-1 {
+			end = loader.Data[idx+1].StartDate
+		}
+		start = latest(start, job.StartTime)
+		if end.After(job.DueTime) {
+			end = job.DueTime
+		}
+		if end.After(start) {
+			intervals = append(intervals, interval{workload.Segment{Start: start, End: end}, loader.Data[idx].CarbonIntensity})
+		}
+	}
+	slices.SortStableFunc(intervals, func(a, b interval) int {
+		if a.intensity < b.intensity {
+			return -1
+		}
+		if a.intensity > b.intensity {
+			return 1
+		}
+		return 0
+	})
+	chosen := make([]workload.Segment, 0)
+	var windows []workload.Segment
+	for _, next := range intervals {
+		chosen = append(chosen, next.segment)
+		windows = mergeSegments(chosen)
+		covered := time.Duration(0)
+		for _, window := range windows {
+			covered += window.End.Sub(window.Start)
+		}
+		need := expected + time.Duration(len(windows)-1)*checkpointOverhead
+		if covered < need {
+			continue
+		}
+		// Trim the excess off the end of the last window
+		windows[len(windows)-1].End = windows[len(windows)-1].End.Add(need - covered)
+		if windows[len(windows)-1].End.Sub(windows[len(windows)-1].Start) <= checkpointOverhead && len(windows) > 1 {
+			continue
+		}
+		segmentedCarbon := 0.0
+		for _, window := range windows {
+			segmentedCarbon += CarbonCalculate(loader, window.Start, window.End, aiModel)
+		}
+		if len(windows) > 1 && segmentedCarbon < contiguousCarbon {
+			return windows, segmentedCarbon, nil
+		}
+		break
+	}
+	return contiguous, contiguousCarbon, nil
+}
+
+// mergeSegments returns the segments in time order with touching ones joined.
+func mergeSegments(segments []workload.Segment) []workload.Segment {
+	sorted := slices.Clone(segments)
+	slices.SortFunc(sorted, func(a, b workload.Segment) int {
+		return a.Start.Compare(b.Start)
+	})
+	merged := make([]workload.Segment, 0, len(sorted))
+	for _, segment := range sorted {
+		if len(merged) > 0 && !segment.Start.After(merged[len(merged)-1].End) {
+			merged[len(merged)-1].End = latest(merged[len(merged)-1].End, segment.End)
+			continue
+		}
+		merged = append(merged, workload.Segment{Start: segment.Start, End: segment.End})
+	}
+	return merged
+}
@@ -0,0 +1,123 @@
+package policies
+
+import (
+	"simulator/pkg/environment"
+	"simulator/pkg/workload"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMergeSegments(t *testing.T) {
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	segment := func(from, to time.Duration) workload.Segment {
+		return workload.Segment{Start: start.Add(from), End: start.Add(to)}
+	}
+	tests := []struct {
+		name     string
+		segments []workload.Segment
+		want     []workload.Segment
+	}{
+		{name: "none", segments: nil, want: []workload.Segment{}},
+		{name: "one", segments: []workload.Segment{segment(0, time.Hour)}, want: []workload.Segment{segment(0, time.Hour)}},
+		{
+			name:     "disjoint out of order",
+			segments: []workload.Segment{segment(3*time.Hour, 4*time.Hour), segment(0, time.Hour)},
+			want:     []workload.Segment{segment(0, time.Hour), segment(3*time.Hour, 4*time.Hour)},
+		},
+		{
+			name:     "touching",
+			segments: []workload.Segment{segment(time.Hour, 2*time.Hour), segment(0, time.Hour), segment(2*time.Hour, 3*time.Hour)},
+			want:     []workload.Segment{segment(0, 3*time.Hour)},
+		},
+		{
+			name:     "overlapping",
+			segments: []workload.Segment{segment(0, 2*time.Hour), segment(time.Hour, 3*time.Hour)},
+			want:     []workload.Segment{segment(0, 3*time.Hour)},
+		},
+		{
+			name:     "contained",
+			segments: []workload.Segment{segment(0, 3*time.Hour), segment(time.Hour, 2*time.Hour)},
+			want:     []workload.Segment{segment(0, 3*time.Hour)},
+		},
+		{
+			name:     "joined and separate",
+			segments: []workload.Segment{segment(5*time.Hour, 6*time.Hour), segment(0, time.Hour), segment(time.Hour, 90*time.Minute)},
+			want:     []workload.Segment{segment(0, 90*time.Minute), segment(5*time.Hour, 6*time.Hour)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original := slices.Clone(test.segments)
+			if got := mergeSegments(test.segments); !slices.Equal(got, test.want) {
+				t.Errorf("mergeSegments = %v, want %v", got, test.want)
+			}
+			if !slices.Equal(test.segments, original) {
+				t.Errorf("mergeSegments reordered its argument")
+			}
+		})
+	}
+}
+
+func TestInterruptibleCarbonEstimate(t *testing.T) {
+	dataLoader := syntheticLoader(t)
+	env := &environment.Environment{Loader: dataLoader}
+	tests := []struct {
+		name        string
+		arrival     time.Duration // From the start of the data
+		slack       time.Duration // From arrival to the deadline
+		safeguardSD float64
+		overhead    time.Duration
+		wantWindows int // Checked when set
+	}{
+		{name: "no overhead", arrival: 3*time.Hour + 17*time.Second, slack: 12 * time.Hour, safeguardSD: 1, wantWindows: 2},
+		{name: "small overhead", arrival: 3*time.Hour + 17*time.Second, slack: 12 * time.Hour, safeguardSD: 1, overhead: time.Minute},
+		{name: "over a day", arrival: 20 * time.Hour, slack: 24 * time.Hour, safeguardSD: 2, overhead: 30 * time.Second},
+		{name: "overhead beyond any saving", arrival: time.Hour, slack: 12 * time.Hour, overhead: 24 * time.Hour, wantWindows: 1},
+		{name: "no slack", arrival: time.Hour, wantWindows: 1},
+		{name: "slack shorter than the run", arrival: time.Hour, slack: 5 * time.Minute, wantWindows: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			arrival := dataLoader.StartDate().Add(test.arrival)
+			job := &workload.Job{ArrivalTime: arrival, StartTime: arrival, DueTime: arrival.Add(test.slack)}
+			windows, carbon, err := InterruptibleCarbonEstimate(env, job, &largeModel, test.safeguardSD, test.overhead)
+			if err != nil {
+				t.Fatalf("InterruptibleCarbonEstimate: %v", err)
+			}
+			_, contiguousCarbon, err := TemporalCarbonEstimate(env, job, &largeModel, test.safeguardSD)
+			if err != nil {
+				t.Fatalf("TemporalCarbonEstimate: %v", err)
+			}
+			if test.wantWindows > 0 && len(windows) != test.wantWindows {
+				t.Fatalf("split the run into %v, want %d windows", windows, test.wantWindows)
+			}
+			if carbon > contiguousCarbon+1e-9 {
+				t.Errorf("windows emit %f gCO2, more than the best contiguous run's %f", carbon, contiguousCarbon)
+			}
+			expected := time.Duration(largeModel.MeanRunTime)*time.Second + time.Duration(largeModel.StdDevRunTime*test.safeguardSD)*time.Second
+			covered, windowCarbon := time.Duration(0), 0.0
+			for i, window := range windows {
+				if !window.End.After(window.Start) {
+					t.Errorf("window %d is empty", i)
+				}
+				if i > 0 && !window.Start.After(windows[i-1].End) {
+					t.Errorf("window %d does not start after window %d ends", i, i-1)
+				}
+				covered += window.End.Sub(window.Start)
+				windowCarbon += CarbonCalculate(dataLoader, window.Start, window.End, &largeModel)
+			}
+			if want := expected + time.Duration(len(windows)-1)*test.overhead; covered != want {
+				t.Errorf("windows cover %v, want %v", covered, want)
+			}
+			if !closeTo(carbon, windowCarbon) {
+				t.Errorf("predicted %f gCO2, the windows emit %f", carbon, windowCarbon)
+			}
+			if len(windows) > 1 {
+				if windows[0].Start.Before(job.StartTime) || windows[len(windows)-1].End.After(job.DueTime) {
+					t.Errorf("windows %v leave the time from arrival to deadline", windows)
+				}
+			}
+		})
+	}
+}
@@ -3,71 +3,70 @@ package policies
 import (
 	"fmt"
 	"math"
-	"math/rand"
 	"simulator/pkg/directory"
+	"simulator/pkg/environment"
 	"simulator/pkg/workload"
+	"strings"
 	"sync"
 	"time"
 )
 
 type ModelSelection struct {
-	requiredAccuracy  float64
-	currTotalAccuracy float64
-	processedJobs     int
+	env              *environment.Environment
+	requiredAccuracy float64
+	constraint       AccuracyConstraint
 }
 
 type ModelSelectionEstimate struct {
+	ExpectedEnd    time.Time
 	CarbonEstimate float64
 	Model          *directory.AIModelDefinition
 }
 
-func NewModelSelection(requiredAccuracy float64) *ModelSelection {
+// NewModelSelection returns the policy enforcing requiredAccuracy through the
+// constraint, or as a global mean when the constraint is nil.
+func NewModelSelection(env *environment.Environment, requiredAccuracy float64, constraint AccuracyConstraint) *ModelSelection {
+	if constraint == nil {
+		constraint = NewGlobalAccuracy(requiredAccuracy)
+	}
 	return &ModelSelection{
-		requiredAccuracy:  requiredAccuracy,
-		currTotalAccuracy: 0,
-		processedJobs:     0,
+		env:              env,
+		requiredAccuracy: requiredAccuracy,
+		constraint:       constraint,
 	}
 }
 
 func (m *ModelSelection) HandleIncoming(job *workload.Job) error {
-	modelDirectory := directory.FetchDirectory()
+	modelDirectory := m.env.Directory
 	if modelDirectory == nil {
 		return fmt.Errorf("model directory not initialized")
 	}
-	models := modelDirectory.GetModels()
+	// Models are visited in a fixed order so ties resolve identically across runs
+	allowed, err := allowedModels(modelDirectory, m.constraint, job)
+	if err != nil {
+		return err
+	}
 	var selectedModel *directory.AIModelDefinition
-	arrayLen := 0
 	var wg sync.WaitGroup
-	for _, model := range models {
-		newAccuracy := (m.currTotalAccuracy + model.Accuracy) / float64(m.processedJobs+1)
-		if newAccuracy >= m.requiredAccuracy {
-			arrayLen++
-		}
-	}
-	if arrayLen == 0 {
-		return fmt.Errorf("no model found that meets the required accuracy, there is likely no model with an accuracy >= to the required accuracy")
-	}
-	wg.Add(arrayLen)
-	array := make([]ModelSelectionEstimate, arrayLen)
-	i := 0
-	for _, model := range models {
-		newAccuracy := (m.currTotalAccuracy + model.Accuracy) / float64(m.processedJobs+1)
-		if newAccuracy >= m.requiredAccuracy {
-			go func(index int, model *directory.AIModelDefinition) {
-				defer wg.Done()
-				carbonEstimate := FIFOCarbonEstimate(job, model)
-				array[index] = ModelSelectionEstimate{
-					CarbonEstimate: carbonEstimate,
-					Model:          model,
-				}
-			}(i, &model)
-			i += 1
-		}
+	wg.Add(len(allowed))
+	array := make([]ModelSelectionEstimate, len(allowed))
+	for i, model := range allowed {
+		go func(index int, model *directory.AIModelDefinition) {
+			defer wg.Done()
+			expectedEnd, carbonEstimate := fifoCarbonPredict(m.env, job, model)
+			array[index] = ModelSelectionEstimate{
+				ExpectedEnd:    expectedEnd,
+				CarbonEstimate: carbonEstimate,
+				Model:          model,
+			}
+		}(i, model)
 	}
 	wg.Wait()
 	bestCarbon := math.MaxFloat64
 	bestAccuracy := math.MaxFloat64
 	for _, estimate := range array {
+		// Predictions are logged here rather than in the goroutines to keep the log order stable
+		logFIFOPrediction(m.env, job, estimate.Model, estimate.ExpectedEnd, estimate.CarbonEstimate)
 		if estimate.CarbonEstimate <= bestCarbon && estimate.Model.Accuracy <= bestAccuracy {
 			bestCarbon = estimate.CarbonEstimate
 			bestAccuracy = estimate.Model.Accuracy
@@ -76,11 +75,12 @@ func (m *ModelSelection) HandleIncoming(job *workload.Job) error {
 	}
 
 	job.Model = selectedModel
-	duration := max(selectedModel.MeanRunTime+selectedModel.StdDevRunTime*rand.NormFloat64(), 0)
+	job.Note("predicted_carbon_g", formatCarbon(bestCarbon))
+	job.Note("candidates", candidateNames(array))
+	duration := max(selectedModel.MeanRunTime+selectedModel.StdDevRunTime*m.env.Rand.NormFloat64(), 0)
 	job.EndTime = job.StartTime.Add(time.Duration(duration) * time.Second)
 
-	m.currTotalAccuracy += selectedModel.Accuracy
-	m.processedJobs++
+	m.constraint.Record(job, selectedModel)
 	return nil
 }
 
@@ -93,5 +93,21 @@ func (m ModelSelection) HandleRunning(job *workload.Job) error {
 }
 
 func (m ModelSelection) String() string {
-	return fmt.Sprintf("Model Selection with required accuracy %f", m.requiredAccuracy)
+	return fmt.Sprintf("Model Selection with required accuracy %f", m.requiredAccuracy) + constraintSuffix(m.constraint)
+}
+
+func candidateNames(estimates []ModelSelectionEstimate) string {
+	names := make([]string, len(estimates))
+	for i, estimate := range estimates {
+		names[i] = estimate.Model.ModelName
+	}
+	return strings.Join(names, ",")
+}
+
+// constraintSuffix names constraints other than the default global mean.
+func constraintSuffix(constraint AccuracyConstraint) string {
+	if _, ok := constraint.(*GlobalAccuracy); ok {
+		return ""
+	}
+	return fmt.Sprintf(" per %s", constraint)
 }
@@ -0,0 +1,459 @@
+package policies
+
+import (
+	"fmt"
+	"math"
+	"simulator/pkg/directory"
+	"simulator/pkg/environment"
+	"simulator/pkg/loader"
+	"simulator/pkg/workload"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Oracle is an offline policy that sees every arrival and realised runtime
+// before the simulation starts. It assigns each job the model and start time
+// that minimise total carbon subject to deadlines, the mean accuracy target
+// and, optionally, cluster capacity, giving a lower bound for the online
+// policies.
+//
+// Models are chosen by Lagrangian relaxation of the accuracy target followed
+// by a greedy repair, and start times by scanning every point where carbon
+// as a function of start time can be minimal. With a capacity limit, jobs are
+// placed least flexible first and then improved by local search.
+type Oracle struct {
+	env              *environment.Environment
+	requiredAccuracy float64                      // Mean accuracy target, 0 for none
+	aiModel          *directory.AIModelDefinition // Restricts the oracle to one model when set
+	capacity         int                          // Cluster capacity, 0 for unlimited
+	slots            map[string]int               // Per-model slot overrides
+
+	plan map[*workload.Job]oraclePlacement
+}
+
+// oracleOption is one way of running a job: a model and its best start time.
+type oracleOption struct {
+	model    *directory.AIModelDefinition
+	runtime  time.Duration
+	start    time.Time
+	carbon   float64
+	deadline time.Time // Latest start that still meets the deadline
+}
+
+type oraclePlacement struct {
+	option oracleOption
+	start  time.Time
+	carbon float64
+}
+
+func NewOracle(env *environment.Environment, requiredAccuracy float64, aiModel *directory.AIModelDefinition, capacity int, slots map[string]int) *Oracle {
+	return &Oracle{
+		env:              env,
+		requiredAccuracy: requiredAccuracy,
+		aiModel:          aiModel,
+		capacity:         capacity,
+		slots:            slots,
+	}
+}
+
+// Plan computes the assignment of every job ahead of the simulation.
+func (o *Oracle) Plan(jobs []*workload.Job) error {
+	if o.env.Loader == nil || o.env.Directory == nil {
+		return fmt.Errorf("loader or model directory not initialized")
+	}
+	models := o.models()
+	if len(models) == 0 {
+		return fmt.Errorf("no models available to the oracle")
+	}
+	// Realised runtimes are drawn up front with one z-score per job, so every
+	// model the oracle considers for a job sees the same draw
+	options := make([][]oracleOption, len(jobs))
+	for i, job := range jobs {
+		z := o.env.Rand.NormFloat64()
+		options[i] = make([]oracleOption, len(models))
+		for j, model := range models {
+			runtime := time.Duration(max(model.MeanRunTime+model.StdDevRunTime*z, 0)) * time.Second
+			option, err := o.bestOption(job, model, runtime)
+			if err != nil {
+				return err
+			}
+			options[i][j] = option
+		}
+	}
+	chosen, err := o.chooseModels(options)
+	if err != nil {
+		return err
+	}
+	o.plan = make(map[*workload.Job]oraclePlacement, len(jobs))
+	if o.capacity <= 0 {
+		for i, job := range jobs {
+			option := options[i][chosen[i]]
+			o.plan[job] = oraclePlacement{option: option, start: option.start, carbon: option.carbon}
+		}
+	} else if err := o.placeWithCapacity(jobs, options, chosen); err != nil {
+		return err
+	}
+	total := 0.0
+	for _, placement := range o.plan {
+		total += placement.carbon
+	}
+	o.env.Logger.Printf("[ORACLE PLAN] Planned %d jobs for a predicted %f gCO2", len(jobs), total)
+	return nil
+}
+
+func (o *Oracle) models() []*directory.AIModelDefinition {
+	if o.aiModel != nil {
+		return []*directory.AIModelDefinition{o.aiModel}
+	}
+	all := o.env.Directory.GetModels()
+	models := make([]*directory.AIModelDefinition, 0, len(all))
+	for _, name := range o.env.Directory.GetModelNames() {
+		model := all[name]
+		models = append(models, &model)
+	}
+	return models
+}
+
+// candidateStarts returns every start time in [earliest, latest] at which the
+// job's start or end meets a change in carbon intensity. Carbon is piecewise
+// linear in the start time between these points, so its minimum lies on one.
+func (o *Oracle) candidateStarts(earliest, latest time.Time, runtime time.Duration) []time.Time {
+	loader := o.env.Loader
+	starts := []time.Time{earliest}
+	if latest.After(earliest) {
+		starts = append(starts, latest)
+	}
+	first := sort.Search(loader.NumEntries(), func(i int) bool {
+		return !loader.Data[i].StartDate.Before(earliest)
+	})
+	for i := first; i < loader.NumEntries() && !loader.Data[i].StartDate.After(latest); i++ {
+		starts = append(starts, loader.Data[i].StartDate)
+	}
+	first = sort.Search(loader.NumEntries(), func(i int) bool {
+		return !loader.Data[i].StartDate.Add(-runtime).Before(earliest)
+	})
+	for i := first; i < loader.NumEntries() && !loader.Data[i].StartDate.Add(-runtime).After(latest); i++ {
+		starts = append(starts, loader.Data[i].StartDate.Add(-runtime))
+	}
+	slices.SortFunc(starts, func(a, b time.Time) int {
+		return a.Compare(b)
+	})
+	return slices.CompactFunc(starts, func(a, b time.Time) bool {
+		return a.Equal(b)
+	})
+}
+
+// windowCarbon returns the carbon of running the model with the window moved
+// to start. Candidate starts come in order, so the window only slides forward.
+func (o *Oracle) windowCarbon(window *loader.Window, start time.Time, model *directory.AIModelDefinition) (float64, error) {
+	intensity, err := window.Integral(start)
+	if err != nil {
+		return 0, fmt.Errorf("error integrating carbon intensity: %w", err)
+	}
+	return modelCarbon(intensity, model), nil
+}
+
+// latestStart returns the last start that finishes by the deadline and within
+// the carbon data, or the arrival when none does.
+func (o *Oracle) latestStart(job *workload.Job, runtime time.Duration) time.Time {
+	latest := job.DueTime.Add(-runtime)
+	if end := o.env.Loader.EndDate().Add(-runtime); end.Before(latest) {
+		latest = end
+	}
+	if latest.Before(job.StartTime) {
+		return job.StartTime
+	}
+	return latest
+}
+
+// bestOption returns the cheapest start of the job on the model, ignoring
+// capacity. The arrival is the first candidate, so ties keep the job there.
+func (o *Oracle) bestOption(job *workload.Job, model *directory.AIModelDefinition, runtime time.Duration) (oracleOption, error) {
+	latest := o.latestStart(job, runtime)
+	best := oracleOption{
+		model:    model,
+		runtime:  runtime,
+		start:    job.StartTime,
+		carbon:   math.Inf(1),
+		deadline: latest,
+	}
+	window := o.env.Loader.NewWindow(runtime)
+	for _, start := range o.candidateStarts(job.StartTime, latest, runtime) {
+		carbon, err := o.windowCarbon(window, start, model)
+		if err != nil {
+			return oracleOption{}, fmt.Errorf("error planning job arriving at %s: %w", job.StartTime.Format(time.RFC3339), err)
+		}
+		if carbon < best.carbon {
+			best.start, best.carbon = start, carbon
+		}
+	}
+	return best, nil
+}
+
+// chooseModels picks one option per job minimising carbon while keeping the
+// mean accuracy at or above the target. For a multiplier lambda each job takes
+// the option minimising carbon - lambda * accuracy; lambda is found by
+// bisection and the remaining shortfall is repaired by the cheapest upgrades.
+// The repair can overshoot, so the result is the cheaper of the repaired and
+// the feasible multiplier's assignments, with any upgrade it no longer needs
+// undone.
+func (o *Oracle) chooseModels(options [][]oracleOption) ([]int, error) {
+	chosen := make([]int, len(options))
+	if len(options) == 0 {
+		return chosen, nil
+	}
+	// Accuracies summed in a different order than the target was multiplied
+	// may fall short of it by rounding alone
+	target := o.requiredAccuracy*float64(len(options)) - 1e-9*float64(len(options))
+	pick := func(lambda float64) float64 {
+		total := 0.0
+		for i, jobOptions := range options {
+			best := math.Inf(1)
+			for j, option := range jobOptions {
+				if cost := option.carbon - lambda*option.model.Accuracy; cost < best {
+					best = cost
+					chosen[i] = j
+				}
+			}
+			total += jobOptions[chosen[i]].model.Accuracy
+		}
+		return total
+	}
+	carbon := func(assignment []int) float64 {
+		total := 0.0
+		for i, j := range assignment {
+			total += options[i][j].carbon
+		}
+		return total
+	}
+	if pick(0) >= target {
+		return chosen, nil
+	}
+	high := 1.0
+	for pick(high) < target {
+		high *= 2
+		if high > 1e12 {
+			return nil, fmt.Errorf("no model assignment meets the required accuracy of %f", o.requiredAccuracy)
+		}
+	}
+	low := 0.0
+	for range 60 {
+		mid := (low + high) / 2
+		if pick(mid) >= target {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	pick(high)
+	feasible := slices.Clone(chosen)
+	// The lower multiplier is cheaper but may fall just short of the target
+	total := pick(low)
+	for total < target {
+		bestJob, bestOption, bestRatio := -1, -1, math.Inf(1)
+		for i, jobOptions := range options {
+			current := jobOptions[chosen[i]]
+			for j, option := range jobOptions {
+				gain := option.model.Accuracy - current.model.Accuracy
+				if gain <= 0 {
+					continue
+				}
+				if ratio := (option.carbon - current.carbon) / gain; ratio < bestRatio {
+					bestJob, bestOption, bestRatio = i, j, ratio
+				}
+			}
+		}
+		if bestJob < 0 {
+			return nil, fmt.Errorf("no model assignment meets the required accuracy of %f", o.requiredAccuracy)
+		}
+		total += options[bestJob][bestOption].model.Accuracy - options[bestJob][chosen[bestJob]].model.Accuracy
+		chosen[bestJob] = bestOption
+	}
+	if carbon(feasible) < carbon(chosen) {
+		chosen = feasible
+	}
+	o.exchange(options, chosen, target)
+	return chosen, nil
+}
+
+// exchange improves a feasible assignment by moving single jobs to cheaper
+// options the accuracy target can spare, undoing upgrades the repair made
+// that a later one made unnecessary.
+func (o *Oracle) exchange(options [][]oracleOption, chosen []int, target float64) {
+	total := 0.0
+	for i, j := range chosen {
+		total += options[i][j].model.Accuracy
+	}
+	for improved := true; improved; {
+		improved = false
+		for i, jobOptions := range options {
+			for j, option := range jobOptions {
+				current := jobOptions[chosen[i]]
+				accuracy := total - current.model.Accuracy + option.model.Accuracy
+				if option.carbon < current.carbon && accuracy >= target {
+					chosen[i], total, improved = j, accuracy, true
+				}
+			}
+		}
+	}
+}
+
+// oracleOccupancy tracks cluster slots in use per carbon data interval. A job
+// occupies every interval it overlaps, which is conservative, so plans that
+// fit never exceed the real capacity.
+type oracleOccupancy struct {
+	o     *Oracle
+	inUse []int
+}
+
+func (c *oracleOccupancy) span(start time.Time, runtime time.Duration) (int, int) {
+	loader := c.o.env.Loader
+	first, err := loader.GetIndexByDate(start)
+	if err != nil {
+		first = 0
+	}
+	last, err := loader.GetIndexByDate(start.Add(runtime))
+	if err != nil {
+		last = loader.NumEntries() - 1
+	}
+	if runtime > 0 && last > first && loader.Data[last].StartDate.Equal(start.Add(runtime)) {
+		last--
+	}
+	return first, last
+}
+
+func (c *oracleOccupancy) fits(start time.Time, option oracleOption) bool {
+	first, last := c.span(start, option.runtime)
+	need := c.o.slotsFor(option.model)
+	for i := first; i <= last; i++ {
+		if c.inUse[i]+need > c.o.capacity {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *oracleOccupancy) add(start time.Time, option oracleOption, delta int) {
+	first, last := c.span(start, option.runtime)
+	need := c.o.slotsFor(option.model) * delta
+	for i := first; i <= last; i++ {
+		c.inUse[i] += need
+	}
+}
+
+func (o *Oracle) slotsFor(model *directory.AIModelDefinition) int {
+	if slots, ok := o.slots[model.ModelName]; ok && slots > 0 {
+		return slots
+	}
+	if model.Slots > 0 {
+		return model.Slots
+	}
+	return 1
+}
+
+// bestFit returns the cheapest start for the option that fits the cluster.
+func (o *Oracle) bestFit(job *workload.Job, option oracleOption, occupancy *oracleOccupancy) (time.Time, float64, bool, error) {
+	bestStart, bestCarbon, found := time.Time{}, math.Inf(1), false
+	window := o.env.Loader.NewWindow(option.runtime)
+	for _, start := range o.candidateStarts(job.StartTime, option.deadline, option.runtime) {
+		if !occupancy.fits(start, option) {
+			continue
+		}
+		carbon, err := o.windowCarbon(window, start, option.model)
+		if err != nil {
+			return time.Time{}, 0, false, fmt.Errorf("error placing job arriving at %s: %w", job.StartTime.Format(time.RFC3339), err)
+		}
+		if carbon < bestCarbon {
+			bestStart, bestCarbon, found = start, carbon, true
+		}
+	}
+	return bestStart, bestCarbon, found, nil
+}
+
+// placeWithCapacity places jobs with the least slack first at their cheapest
+// start that fits, then repeatedly moves each job to a cheaper start that the
+// others leave free until no move helps.
+func (o *Oracle) placeWithCapacity(jobs []*workload.Job, options [][]oracleOption, chosen []int) error {
+	occupancy := &oracleOccupancy{o: o, inUse: make([]int, o.env.Loader.NumEntries())}
+	order := make([]int, len(jobs))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		slackA := options[a][chosen[a]].deadline.Sub(jobs[a].StartTime)
+		slackB := options[b][chosen[b]].deadline.Sub(jobs[b].StartTime)
+		return int(slackA - slackB)
+	})
+	unplaced := 0
+	for _, i := range order {
+		option := options[i][chosen[i]]
+		start, carbon, found, err := o.bestFit(jobs[i], option, occupancy)
+		if err != nil {
+			return err
+		}
+		if !found {
+			// The simulator will hold the job until capacity frees up
+			start = jobs[i].StartTime
+			if carbon, err = o.windowCarbon(o.env.Loader.NewWindow(option.runtime), start, option.model); err != nil {
+				return err
+			}
+			unplaced++
+		}
+		occupancy.add(start, option, 1)
+		o.plan[jobs[i]] = oraclePlacement{option: option, start: start, carbon: carbon}
+	}
+	if unplaced > 0 {
+		o.env.Logger.Printf("[ORACLE PLAN] %d jobs could not be placed within capacity before their deadlines", unplaced)
+	}
+	for pass := 0; pass < 10; pass++ {
+		improved := false
+		for _, i := range order {
+			placement := o.plan[jobs[i]]
+			occupancy.add(placement.start, placement.option, -1)
+			start, carbon, found, err := o.bestFit(jobs[i], placement.option, occupancy)
+			if err != nil {
+				return err
+			}
+			if found && carbon < placement.carbon {
+				placement.start, placement.carbon = start, carbon
+				o.plan[jobs[i]] = placement
+				improved = true
+			}
+			occupancy.add(placement.start, placement.option, 1)
+		}
+		if !improved {
+			break
+		}
+	}
+	return nil
+}
+
+func (o *Oracle) HandleIncoming(job *workload.Job) error {
+	placement, ok := o.plan[job]
+	if !ok {
+		return fmt.Errorf("job arriving at %v was not planned by the oracle", job.StartTime.Format(time.ANSIC))
+	}
+	o.env.Logger.Printf("[ORACLE] For start time %s and model %s, total carbon is %f gCO2", placement.start.Format(time.ANSIC), placement.option.model.ModelName, placement.carbon)
+	job.Model = placement.option.model
+	job.Note("predicted_carbon_g", formatCarbon(placement.carbon))
+	job.Note("shift", placement.start.Sub(job.StartTime).String())
+	job.StartTime = placement.start
+	job.EndTime = placement.start.Add(placement.option.runtime)
+	return nil
+}
+
+func (o *Oracle) HandleQueued(job *workload.Job) error {
+	return nil
+}
+
+func (o *Oracle) HandleRunning(job *workload.Job) error {
+	return nil
+}
+
+func (o *Oracle) String() string {
+	if o.aiModel != nil {
+		return fmt.Sprintf("Oracle with %s", o.aiModel.ModelName)
+	}
+	return fmt.Sprintf("Oracle with required accuracy %f", o.requiredAccuracy)
+}
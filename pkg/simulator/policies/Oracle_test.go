@@ -0,0 +1,159 @@
+package policies
+
+import (
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"simulator/pkg/directory"
+	"simulator/pkg/environment"
+	"simulator/pkg/workload"
+	"testing"
+	"time"
+)
+
+// oracleOptions returns the options of running each of jobs jobs, spread over
+// the data, at its arrival with each model.
+func oracleOptions(t *testing.T, jobs int, models ...*directory.AIModelDefinition) [][]oracleOption {
+	t.Helper()
+	dataLoader := syntheticLoader(t)
+	options := make([][]oracleOption, jobs)
+	for i := range options {
+		start := dataLoader.StartDate().Add(time.Duration(i)*5*time.Hour + time.Duration(i)*7*time.Minute)
+		for _, model := range models {
+			runtime := time.Duration(model.MeanRunTime) * time.Second
+			options[i] = append(options[i], oracleOption{
+				model:   model,
+				runtime: runtime,
+				start:   start,
+				carbon:  CarbonCalculate(dataLoader, start, start.Add(runtime), model),
+			})
+		}
+	}
+	return options
+}
+
+// cheapestAssignment finds the cheapest assignment meeting the target by
+// trying every one.
+func cheapestAssignment(options [][]oracleOption, requiredAccuracy float64) float64 {
+	target := requiredAccuracy*float64(len(options)) - 1e-9
+	best := math.Inf(1)
+	var search func(job int, carbon, accuracy float64)
+	search = func(job int, carbon, accuracy float64) {
+		if job == len(options) {
+			if accuracy >= target {
+				best = min(best, carbon)
+			}
+			return
+		}
+		for _, option := range options[job] {
+			search(job+1, carbon+option.carbon, accuracy+option.model.Accuracy)
+		}
+	}
+	search(0, 0, 0)
+	return best
+}
+
+func TestOracleChooseModels(t *testing.T) {
+	allModels := []*directory.AIModelDefinition{&smallModel, &mediumModel, &largeModel}
+	tests := []struct {
+		name             string
+		jobs             int
+		models           []*directory.AIModelDefinition
+		requiredAccuracy float64
+		wantErr          bool
+	}{
+		{name: "no target", jobs: 5, models: allModels},
+		{name: "target met by the cheapest model", jobs: 5, models: allModels, requiredAccuracy: 0.5},
+		{name: "one job", jobs: 1, models: allModels, requiredAccuracy: 0.7},
+		{name: "mixing models", jobs: 6, models: allModels, requiredAccuracy: 0.7},
+		{name: "target between the larger models", jobs: 3, models: allModels, requiredAccuracy: 0.8},
+		{name: "tight target", jobs: 7, models: allModels, requiredAccuracy: 0.85},
+		{name: "only the most accurate model", jobs: 3, models: allModels, requiredAccuracy: 0.9},
+		{name: "only the most accurate model for many jobs", jobs: 9, models: allModels, requiredAccuracy: 0.9},
+		{name: "restricted to one model", jobs: 4, models: []*directory.AIModelDefinition{&mediumModel}, requiredAccuracy: 0.75},
+		{name: "target out of reach", jobs: 4, models: allModels, requiredAccuracy: 0.95, wantErr: true},
+		{name: "restricted model below target", jobs: 4, models: []*directory.AIModelDefinition{&smallModel}, requiredAccuracy: 0.6, wantErr: true},
+		{name: "no jobs", jobs: 0, models: allModels, requiredAccuracy: 0.9},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := oracleOptions(t, test.jobs, test.models...)
+			oracle := &Oracle{requiredAccuracy: test.requiredAccuracy}
+			chosen, err := oracle.chooseModels(options)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("chooseModels = %v, want an error", chosen)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("chooseModels: %v", err)
+			}
+			if len(chosen) != test.jobs {
+				t.Fatalf("chose %d options for %d jobs", len(chosen), test.jobs)
+			}
+			carbon, accuracy := 0.0, 0.0
+			for i, j := range chosen {
+				carbon += options[i][j].carbon
+				accuracy += options[i][j].model.Accuracy
+			}
+			if test.jobs > 0 && accuracy/float64(test.jobs) < test.requiredAccuracy-1e-9 {
+				t.Errorf("mean accuracy %f is below the target %f", accuracy/float64(test.jobs), test.requiredAccuracy)
+			}
+			if want := cheapestAssignment(options, test.requiredAccuracy); test.jobs > 0 && !closeTo(carbon, want) {
+				t.Errorf("assignment emits %f gCO2, the cheapest %f", carbon, want)
+			}
+		})
+	}
+}
+
+func TestOraclePlan(t *testing.T) {
+	dataLoader := syntheticLoader(t)
+	start := dataLoader.StartDate()
+	tests := []struct {
+		name     string
+		capacity int
+		arrivals []time.Duration // From the start of the data
+		wantErr  bool
+	}{
+		{name: "unlimited capacity", arrivals: []time.Duration{time.Hour, 2 * time.Hour, 30 * time.Hour}},
+		{name: "limited capacity", capacity: 2, arrivals: []time.Duration{time.Hour, time.Hour, time.Hour, 2 * time.Hour}},
+		{name: "arrival before the data", arrivals: []time.Duration{time.Hour, -time.Hour}, wantErr: true},
+		{name: "arrival before the data with limited capacity", capacity: 2, arrivals: []time.Duration{-time.Hour}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := &environment.Environment{
+				Loader:    dataLoader,
+				Directory: testDirectory(t),
+				Logger:    log.New(io.Discard, "", 0),
+				Rand:      rand.New(rand.NewSource(1)),
+			}
+			jobs := make([]*workload.Job, len(test.arrivals))
+			for i, arrival := range test.arrivals {
+				jobs[i] = &workload.Job{StartTime: start.Add(arrival), DueTime: start.Add(arrival + 6*time.Hour)}
+			}
+			oracle := NewOracle(env, 0, &mediumModel, test.capacity, nil)
+			err := oracle.Plan(jobs)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("Plan succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+			for i, job := range jobs {
+				placement := oracle.plan[job]
+				if placement.start.Before(job.StartTime) || placement.start.After(job.DueTime) {
+					t.Errorf("job %d planned to start at %v, outside %v to %v", i, placement.start, job.StartTime, job.DueTime)
+				}
+				if want := CarbonCalculate(dataLoader, placement.start, placement.start.Add(placement.option.runtime), &mediumModel); !closeTo(placement.carbon, want) {
+					t.Errorf("job %d planned at %f gCO2, its start emits %f", i, placement.carbon, want)
+				}
+			}
+		})
+	}
+}
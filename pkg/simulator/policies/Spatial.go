@@ -0,0 +1,100 @@
+package policies
+
+import (
+	"fmt"
+	"math"
+	"simulator/pkg/directory"
+	"simulator/pkg/environment"
+	"simulator/pkg/workload"
+	"strings"
+	"time"
+)
+
+// Spatial routes each job to the region predicted to emit the least carbon,
+// counting the transfer of the job's data and the network latency before it
+// can start there. With shifting enabled it also picks the best start within
+// each region, as the temporal policy does.
+type Spatial struct {
+	env         *environment.Environment
+	aiModel     *directory.AIModelDefinition
+	safeguardSD float64
+	shift       bool
+}
+
+func NewSpatial(env *environment.Environment, aiModel *directory.AIModelDefinition, safeguardSD float64, shift bool) *Spatial {
+	return &Spatial{
+		env:         env,
+		aiModel:     aiModel,
+		safeguardSD: safeguardSD,
+		shift:       shift,
+	}
+}
+
+func (s *Spatial) HandleIncoming(job *workload.Job) error {
+	if len(s.env.Regions) == 0 {
+		return fmt.Errorf("the spatial policies require regions to route jobs to")
+	}
+	job.Model = s.aiModel
+	bestRegion, bestStart, bestCarbon := "", job.StartTime, math.MaxFloat64
+	candidates := make([]string, 0, len(s.env.Regions))
+	for _, region := range s.env.Regions {
+		candidate := *job
+		candidate.Region = region.Name
+		candidate.StartTime = job.StartTime.Add(region.Latency)
+		expectedEnd := candidate.StartTime.Add(time.Duration(s.aiModel.MeanRunTime) * time.Second)
+		if candidate.StartTime.Before(region.Loader.StartDate()) || expectedEnd.After(region.Loader.EndDate()) {
+			// The region's data does not cover the job
+			continue
+		}
+		start, carbon := candidate.StartTime, 0.0
+		if s.shift {
+			var err error
+			if start, carbon, err = TemporalCarbonEstimate(s.env, &candidate, s.aiModel, s.safeguardSD); err != nil {
+				return err
+			}
+		} else {
+			_, carbon = fifoCarbonPredict(s.env, &candidate, s.aiModel)
+		}
+		carbon += region.TransferCarbon
+		s.env.Logger.Printf("[SPATIAL PREDICT] For region %s, start time %s, and model %s, total carbon is predicted %f gCO2", region.Name, start.Format(time.ANSIC), s.aiModel.ModelName, carbon)
+		candidates = append(candidates, region.Name)
+		if carbon < bestCarbon {
+			bestRegion, bestStart, bestCarbon = region.Name, start, carbon
+		}
+	}
+	if bestRegion == "" {
+		return fmt.Errorf("no region has carbon data covering the job arriving at %v", job.StartTime.Format(time.ANSIC))
+	}
+	job.Region = bestRegion
+	job.Note("predicted_carbon_g", formatCarbon(bestCarbon))
+	job.Note("candidates", strings.Join(candidates, ","))
+	job.Note("shift", bestStart.Sub(job.StartTime).String())
+	job.StartTime = bestStart
+	duration := max(s.aiModel.MeanRunTime+s.aiModel.StdDevRunTime*s.env.Rand.NormFloat64(), 0)
+	job.EndTime = job.StartTime.Add(time.Duration(duration) * time.Second)
+	return nil
+}
+
+// Replan looks for a cheaper later start in the job's region once the planned
+// start arrives, when shifting is enabled.
+func (s *Spatial) Replan(job *workload.Job) (bool, error) {
+	if !s.shift {
+		return false, nil
+	}
+	return replanJob(s.env, job, s.safeguardSD)
+}
+
+func (s *Spatial) HandleQueued(job *workload.Job) error {
+	return nil
+}
+
+func (s *Spatial) HandleRunning(job *workload.Job) error {
+	return nil
+}
+
+func (s *Spatial) String() string {
+	if s.shift {
+		return fmt.Sprintf("Spatiotemporal with %s across %d regions and Standard Deviation Guard: %f", s.aiModel.ModelName, len(s.env.Regions), s.safeguardSD)
+	}
+	return fmt.Sprintf("Spatial with %s across %d regions", s.aiModel.ModelName, len(s.env.Regions))
+}
@@ -2,21 +2,22 @@ package policies
 
 import (
 	"fmt"
-	"log"
-	"math/rand"
 	"simulator/pkg/directory"
-	"simulator/pkg/loader"
+	"simulator/pkg/environment"
 	"simulator/pkg/workload"
+	"strconv"
 	"time"
 )
 
 type Temporal struct {
+	env         *environment.Environment
 	aiModel     *directory.AIModelDefinition
 	safeguardSD float64
 }
 
-func NewTemporal(aiModel *directory.AIModelDefinition, safeguardSD float64) *Temporal {
+func NewTemporal(env *environment.Environment, aiModel *directory.AIModelDefinition, safeguardSD float64) *Temporal {
 	return &Temporal{
+		env:         env,
 		aiModel:     aiModel,
 		safeguardSD: safeguardSD,
 	}
@@ -25,20 +26,28 @@ func NewTemporal(aiModel *directory.AIModelDefinition, safeguardSD float64) *Tem
 func (t *Temporal) HandleIncoming(job *workload.Job) error {
 	// Assign model job
 	job.Model = t.aiModel
-	bestTime, carbonPredict, _ := TemporalCarbonEstimate(job, t.aiModel, t.safeguardSD)
+	bestTime, carbonPredict, _ := TemporalCarbonEstimate(t.env, job, t.aiModel, t.safeguardSD)
 	if !bestTime.Equal(job.StartTime) {
 		estimatedEnd := bestTime.Add(time.Duration(t.aiModel.MeanRunTime) * time.Second).Add(time.Duration(t.aiModel.StdDevRunTime*t.safeguardSD) * time.Second)
-		log.Printf("[TEMPORAL SHIFT PREDICT] For start time %s, estimated end %s, and model %s, total carbon is predicted %f gCO2", bestTime.Format(time.ANSIC), estimatedEnd.Format(time.ANSIC), t.aiModel.ModelName, carbonPredict)
+		t.env.Logger.Printf("[TEMPORAL SHIFT PREDICT] For start time %s, estimated end %s, and model %s, total carbon is predicted %f gCO2", bestTime.Format(time.ANSIC), estimatedEnd.Format(time.ANSIC), t.aiModel.ModelName, carbonPredict)
 	} else {
 		estimatedEnd := job.StartTime.Add(time.Duration(t.aiModel.MeanRunTime) * time.Second).Add(time.Duration(t.aiModel.StdDevRunTime*t.safeguardSD) * time.Second)
-		log.Printf("[TEMPORAL NO CHANGE PREDICT] For start time %s, estimated end %s, and model %s, total carbon is predicted %f gCO2", job.StartTime.Format(time.ANSIC), estimatedEnd.Format(time.ANSIC), t.aiModel.ModelName, carbonPredict)
+		t.env.Logger.Printf("[TEMPORAL NO CHANGE PREDICT] For start time %s, estimated end %s, and model %s, total carbon is predicted %f gCO2", job.StartTime.Format(time.ANSIC), estimatedEnd.Format(time.ANSIC), t.aiModel.ModelName, carbonPredict)
 	}
+	job.Note("predicted_carbon_g", formatCarbon(carbonPredict))
+	job.Note("shift", bestTime.Sub(job.StartTime).String())
 	job.StartTime = bestTime
-	duration := max(t.aiModel.MeanRunTime+t.aiModel.StdDevRunTime*rand.NormFloat64(), 0)
+	duration := max(t.aiModel.MeanRunTime+t.aiModel.StdDevRunTime*t.env.Rand.NormFloat64(), 0)
 	job.EndTime = job.StartTime.Add(time.Duration(duration) * time.Second)
 	return nil
 }
 
+// Replan looks for a cheaper later start once the planned one arrives, which
+// only helps when the forecast horizon hid part of the job's slack.
+func (t *Temporal) Replan(job *workload.Job) (bool, error) {
+	return replanJob(t.env, job, t.safeguardSD)
+}
+
 func (t *Temporal) HandleQueued(job *workload.Job) error {
 	return nil
 }
@@ -51,38 +60,75 @@ func (t *Temporal) String() string {
 	return fmt.Sprintf("Temporal with %s and Standard Deviation Guard: %f", t.aiModel.ModelName, t.safeguardSD)
 }
 
-func TemporalCarbonEstimate(job *workload.Job, aiModel *directory.AIModelDefinition, safeguardSD float64) (time.Time, float64, error) {
-	loader := loader.GetLoader()
-	if loader == nil {
+func TemporalCarbonEstimate(env *environment.Environment, job *workload.Job, aiModel *directory.AIModelDefinition, safeguardSD float64) (time.Time, float64, error) {
+	if env.Loader == nil {
 		return time.Time{}, 0, fmt.Errorf("loader not initialized")
 	}
-	if loader.NumEntries() == 0 {
+	if env.Loader.NumEntries() == 0 {
 		return time.Time{}, 0, fmt.Errorf("loader has no data")
 	}
 	// Default values should there not be space to temporally shift
 	bestTime := job.StartTime
 	currTime := job.StartTime
 	currEnd := job.StartTime.Add(time.Duration(aiModel.MeanRunTime) * time.Second).Add(time.Duration(aiModel.StdDevRunTime*safeguardSD) * time.Second)
-	minCarbon := CarbonCalculate(job.StartTime, currEnd, aiModel)
+	// Start times are chosen from the forecast issued as the job arrives
+	loader := forecastLoader(env, job.Region, job.StartTime, latest(job.DueTime, currEnd))
+	minCarbon := CarbonCalculate(loader, job.StartTime, currEnd, aiModel)
 
-	for currEnd.Before(loader.EndDate()) && currEnd.Before(job.DueTime) {
-		carbonIdx, err := loader.GetIndexByDate(currTime)
-		if err != nil {
-			return time.Time{}, 0, fmt.Errorf("error getting index by date: %v", err)
+	// Candidate starts step through the data one entry at a time, and the
+	// window integrating each run slides along with them
+	window := loader.NewWindow(currEnd.Sub(currTime))
+	carbonIdx, indexErr := loader.GetIndexByDate(currTime)
+	if indexErr != nil {
+		if currTime.After(loader.EndDate()) {
+			// The job starts past the data, there is nothing later to shift to
+			return bestTime, minCarbon, nil
 		}
+		return time.Time{}, 0, fmt.Errorf("error getting index by date: %v", indexErr)
+	}
+	for currEnd.Before(loader.EndDate()) && currEnd.Before(job.DueTime) {
 		if carbonIdx >= loader.NumEntries()-1 {
 			// We can't shift the job to a later time
 			return bestTime, minCarbon, nil
 		}
-		carbon := CarbonCalculate(currTime, currEnd, aiModel)
+		intensity, err := window.Integral(currTime)
+		if err != nil {
+			return time.Time{}, 0, fmt.Errorf("error integrating carbon intensity: %v", err)
+		}
+		carbon := modelCarbon(intensity, aiModel)
 		if carbon < minCarbon {
 			// log.Printf("[TEMPORAL PREDICT] For start time %s, estimated end %s, and model %s, total carbon is predicted %f gCO2", currTime.Format(time.ANSIC), currEnd.Format(time.ANSIC), aiModel.ModelName, carbon)
 			minCarbon = carbon
 			bestTime = currTime
 		}
-		currTime = loader.Data[carbonIdx+1].StartDate
+		carbonIdx++
+		currTime = loader.Data[carbonIdx].StartDate
 		currEnd = currTime.Add(time.Duration(aiModel.MeanRunTime) * time.Second).Add(time.Duration(aiModel.StdDevRunTime*safeguardSD) * time.Second)
 	}
 
 	return bestTime, minCarbon, nil
 }
+
+// replanJob moves the job to the cheapest start the current forecast shows
+// between now, its planned start, and its deadline. The model and runtime are
+// kept. Without a forecast horizon the original plan already saw every start.
+func replanJob(env *environment.Environment, job *workload.Job, safeguardSD float64) (bool, error) {
+	if env.ForecastFor(job.Region).Horizon() == 0 {
+		return false, nil
+	}
+	bestTime, carbonPredict, err := TemporalCarbonEstimate(env, job, job.Model, safeguardSD)
+	if err != nil {
+		return false, err
+	}
+	if !bestTime.After(job.StartTime) {
+		return false, nil
+	}
+	env.Logger.Printf("[TEMPORAL REPLAN PREDICT] For start time %s and model %s, total carbon is predicted %f gCO2", bestTime.Format(time.ANSIC), job.Model.ModelName, carbonPredict)
+	job.EndTime = job.EndTime.Add(bestTime.Sub(job.StartTime))
+	job.StartTime = bestTime
+	job.Note("predicted_carbon_g", formatCarbon(carbonPredict))
+	job.Note("shift", bestTime.Sub(job.ArrivalTime).String())
+	replans, _ := strconv.Atoi(job.Decision["replans"])
+	job.Note("replans", strconv.Itoa(replans+1))
+	return true, nil
+}
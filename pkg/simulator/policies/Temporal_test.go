@@ -0,0 +1,110 @@
+package policies
+
+import (
+	"io"
+	"log"
+	"simulator/pkg/environment"
+	"simulator/pkg/forecast"
+	"simulator/pkg/loader"
+	"simulator/pkg/workload"
+	"testing"
+	"time"
+)
+
+// stepLoader returns six hours of data every five minutes that drops from a
+// high intensity to a low one after the first hour.
+func stepLoader() *loader.Loader {
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	data := make([]*loader.DataPoint, 72)
+	for i := range data {
+		intensity := 100.0
+		if i < 12 {
+			intensity = 500
+		}
+		data[i] = &loader.DataPoint{StartDate: start.Add(time.Duration(i) * 5 * time.Minute), CarbonIntensity: intensity}
+	}
+	return loader.NewLoaderFromData("step", data, time.UTC)
+}
+
+func TestReplanJobUsesRoutedForecast(t *testing.T) {
+	truth := stepLoader()
+	limited := forecast.New(truth, forecast.Model{Horizon: 3 * time.Hour}, 1)
+	tests := []struct {
+		name           string
+		homeForecast   *forecast.Forecast
+		regionForecast *forecast.Forecast
+		region         string
+		wantMoved      bool
+	}{
+		{name: "home job with a horizon", homeForecast: limited, wantMoved: true},
+		{name: "home job without a horizon", regionForecast: limited},
+		{name: "routed job with a horizon", regionForecast: limited, region: "west", wantMoved: true},
+		{name: "routed job without a horizon", homeForecast: limited, region: "west"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := &environment.Environment{
+				Loader:   truth,
+				Forecast: test.homeForecast,
+				Regions:  []environment.Region{{Name: "west", Loader: truth, Forecast: test.regionForecast}},
+				Logger:   log.New(io.Discard, "", 0),
+			}
+			start := truth.StartDate()
+			job := &workload.Job{
+				Model:       &smallModel,
+				ArrivalTime: start,
+				StartTime:   start,
+				EndTime:     start.Add(2 * time.Minute),
+				DueTime:     start.Add(5 * time.Hour),
+				Region:      test.region,
+			}
+			moved, err := replanJob(env, job, 0)
+			if err != nil {
+				t.Fatalf("replanJob: %v", err)
+			}
+			if moved != test.wantMoved {
+				t.Fatalf("replanJob moved the job: %v, want %v", moved, test.wantMoved)
+			}
+			if moved && job.StartTime.Before(start.Add(time.Hour)) {
+				t.Errorf("job moved to %v, still in the expensive hour", job.StartTime)
+			}
+			if runtime := job.EndTime.Sub(job.StartTime); runtime != 2*time.Minute {
+				t.Errorf("replanning changed the runtime to %v", runtime)
+			}
+		})
+	}
+}
+
+func TestTemporalCarbonEstimate(t *testing.T) {
+	truth := stepLoader()
+	env := &environment.Environment{Loader: truth, Logger: log.New(io.Discard, "", 0)}
+	tests := []struct {
+		name    string
+		arrival time.Duration // From the start of the data
+		slack   time.Duration
+		want    time.Duration // Best start from the start of the data
+	}{
+		{name: "waits for the drop", slack: 3 * time.Hour, want: time.Hour},
+		{name: "deadline before the drop", slack: 30 * time.Minute, want: 0},
+		{name: "already past the drop", arrival: 2 * time.Hour, slack: 3 * time.Hour, want: 2 * time.Hour},
+		{name: "run reaching past the data", arrival: 6*time.Hour - time.Minute, slack: time.Hour, want: 6*time.Hour - time.Minute},
+		{name: "arriving after the data", arrival: 7 * time.Hour, slack: time.Hour, want: 7 * time.Hour},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			arrival := truth.StartDate().Add(test.arrival)
+			job := &workload.Job{ArrivalTime: arrival, StartTime: arrival, DueTime: arrival.Add(test.slack)}
+			bestTime, carbon, err := TemporalCarbonEstimate(env, job, &smallModel, 0)
+			if err != nil {
+				t.Fatalf("TemporalCarbonEstimate: %v", err)
+			}
+			if want := truth.StartDate().Add(test.want); !bestTime.Equal(want) {
+				t.Errorf("best start %v, want %v", bestTime, want)
+			}
+			runtime := time.Duration(smallModel.MeanRunTime) * time.Second
+			if want := CarbonCalculate(truth, bestTime, bestTime.Add(runtime), &smallModel); !closeTo(carbon, want) {
+				t.Errorf("predicted %f gCO2, running then emits %f", carbon, want)
+			}
+		})
+	}
+}
@@ -11,3 +11,15 @@ type PolicyInterface interface {
 
 	String() string
 }
+
+// ReplannerInterface is implemented by policies that may revise a job's start
+// once it arrives, for instance because more of the forecast is now visible.
+type ReplannerInterface interface {
+	Replan(job *workload.Job) (bool, error) // Reports whether the job was moved to a later start
+}
+
+// PlannerInterface is implemented by offline policies that see the whole
+// workload before the simulation starts.
+type PlannerInterface interface {
+	Plan(jobs []*workload.Job) error // Called once with every job before the first arrival
+}
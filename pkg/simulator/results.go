@@ -0,0 +1,278 @@
+package simulator
+
+import (
+	"simulator/pkg/workload"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Results summarises a completed simulation in a machine-readable form.
+type Results struct {
+	Policy string `json:"policy"`
+	Seed   int64  `json:"seed"`
+
+	TotalCarbon   float64            `json:"total_carbon_g"`    // in gCO2
+	CarbonByModel map[string]float64 `json:"carbon_by_model_g"` // in gCO2
+
+	// Carbon in the signal the policy optimised, when accounting reports
+	// another, in gCO2. Transfers between regions are left out
+	DecisionCarbon *float64 `json:"decision_carbon_g,omitempty"`
+
+	SLOTimeouts        int            `json:"slo_timeouts"`
+	SLOTimeoutsByModel map[string]int `json:"slo_timeouts_by_model"`
+	SLOTimeoutsByClass map[string]int `json:"slo_timeouts_by_class,omitempty"` // Only for workloads with named SLO classes
+
+	JobsCompleted int            `json:"jobs_completed"`
+	JobsByModel   map[string]int `json:"jobs_by_model"`
+	JobsByClass   map[string]int `json:"jobs_by_class,omitempty"`
+
+	// Breakdown by mixture component, only for mixture workloads
+	JobsByComponent        map[string]int     `json:"jobs_by_component,omitempty"`
+	CarbonByComponent      map[string]float64 `json:"carbon_by_component_g,omitempty"` // in gCO2
+	SLOTimeoutsByComponent map[string]int     `json:"slo_timeouts_by_component,omitempty"`
+	MeanAccuracy           float64            `json:"mean_accuracy"` // Mean accuracy of the models that served each job
+
+	// Jobs after which each accuracy constraint failed to hold, keyed by constraint
+	AccuracyViolations map[string]int `json:"accuracy_violations,omitempty"`
+
+	// Breakdown by region, only for multi-region runs
+	JobsByRegion   map[string]int     `json:"jobs_by_region,omitempty"`
+	CarbonByRegion map[string]float64 `json:"carbon_by_region_g,omitempty"` // in gCO2
+
+	QueueingDelay float64 `json:"queueing_delay_s"` // Total time jobs waited for capacity, in seconds
+	DelayedJobs   int     `json:"delayed_jobs"`
+	Pauses        int     `json:"pauses,omitempty"` // Times running jobs were suspended to resume later
+
+	StartTime time.Time `json:"start_time"` // Time of the first simulated event
+	EndTime   time.Time `json:"end_time"`   // Time of the last simulated event
+	Span      float64   `json:"span_s"`     // Simulated time between the first and last event, in seconds
+}
+
+// Results computes the results of the simulation so far.
+func (s *Simulator) Results() *Results {
+	results := &Results{
+		Policy:             s.schedulingPolicy.String(),
+		Seed:               s.env.Seed,
+		TotalCarbon:        s.TotalCarbon(),
+		CarbonByModel:      s.CarbonEmission(),
+		SLOTimeouts:        s.TotalSLOTimeouts(),
+		SLOTimeoutsByModel: s.SLOTimeouts(),
+		JobsByModel:        make(map[string]int),
+		QueueingDelay:      s.queueingDelay,
+		DelayedJobs:        s.delayedJobs,
+		Pauses:             s.pauses,
+		StartTime:          s.firstEventTime,
+		EndTime:            s.currTime,
+	}
+	totalAccuracy := 0.0
+	for _, job := range s.completedJobs {
+		results.JobsCompleted++
+		results.JobsByModel[job.Model.ModelName]++
+		totalAccuracy += job.Model.Accuracy
+		if job.SLOClass != "" {
+			if results.JobsByClass == nil {
+				results.JobsByClass = make(map[string]int)
+				results.SLOTimeoutsByClass = make(map[string]int)
+			}
+			results.JobsByClass[job.SLOClass]++
+			if job.DueTime.Before(job.EndTime) {
+				results.SLOTimeoutsByClass[job.SLOClass]++
+			}
+		}
+		if job.Region != "" {
+			if results.JobsByRegion == nil {
+				results.JobsByRegion = make(map[string]int)
+				results.CarbonByRegion = make(map[string]float64)
+			}
+			results.JobsByRegion[job.Region]++
+			results.CarbonByRegion[job.Region] += job.Carbon
+		}
+		if job.Component != "" {
+			if results.JobsByComponent == nil {
+				results.JobsByComponent = make(map[string]int)
+				results.CarbonByComponent = make(map[string]float64)
+				results.SLOTimeoutsByComponent = make(map[string]int)
+			}
+			results.JobsByComponent[job.Component]++
+			results.CarbonByComponent[job.Component] += job.Carbon
+			if job.DueTime.Before(job.EndTime) {
+				results.SLOTimeoutsByComponent[job.Component]++
+			}
+		}
+	}
+	if results.JobsCompleted > 0 {
+		results.MeanAccuracy = totalAccuracy / float64(results.JobsCompleted)
+	}
+	if s.accuracyTarget > 0 {
+		results.AccuracyViolations = s.accuracyViolations()
+	}
+	if s.signalsDiffer {
+		decisionCarbon := s.decisionCarbon
+		results.DecisionCarbon = &decisionCarbon
+	}
+	if !results.StartTime.IsZero() {
+		results.Span = results.EndTime.Sub(results.StartTime).Seconds()
+	}
+	return results
+}
+
+// accuracyViolations replays the completed jobs in arrival order through each
+// constraint, counting the jobs after which it no longer held.
+func (s *Simulator) accuracyViolations() map[string]int {
+	jobs := slices.Clone(s.completedJobs)
+	slices.SortStableFunc(jobs, func(a, b *workload.Job) int {
+		return a.ArrivalTime.Compare(b.ArrivalTime)
+	})
+	violations := make(map[string]int, len(s.accuracyConstraints))
+	for _, newConstraint := range s.accuracyConstraints {
+		// Each replay needs a constraint with no history
+		constraint := newConstraint()
+		count := 0
+		for _, job := range jobs {
+			if !constraint.Allows(job, job.Model) {
+				count++
+			}
+			constraint.Record(job, job.Model)
+		}
+		violations[constraint.String()] = count
+	}
+	return violations
+}
+
+// ModelNames returns every model that appears in the results, sorted.
+func (r *Results) ModelNames() []string {
+	names := make([]string, 0, len(r.JobsByModel))
+	for name := range r.JobsByModel {
+		names = append(names, name)
+	}
+	for name := range r.CarbonByModel {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// CSVColumns names the keyed columns of the CSV rows of a set of results, so
+// runs with different models, classes, components or regions share one
+// header.
+type CSVColumns struct {
+	Models              []string
+	Classes             []string // Named SLO classes
+	Components          []string // Mixture workload components
+	Regions             []string
+	AccuracyConstraints []string
+	DecisionCarbon      bool // Whether any run accounted in another signal than it optimised
+}
+
+// NewCSVColumns returns the columns covering every key found in results.
+func NewCSVColumns(results []*Results) CSVColumns {
+	var columns CSVColumns
+	for _, result := range results {
+		columns.Models = appendKeys(appendKeys(columns.Models, result.JobsByModel), result.CarbonByModel)
+		columns.Classes = appendKeys(appendKeys(columns.Classes, result.JobsByClass), result.SLOTimeoutsByClass)
+		columns.Components = appendKeys(appendKeys(columns.Components, result.JobsByComponent), result.CarbonByComponent)
+		columns.Regions = appendKeys(appendKeys(columns.Regions, result.JobsByRegion), result.CarbonByRegion)
+		columns.AccuracyConstraints = appendKeys(columns.AccuracyConstraints, result.AccuracyViolations)
+		columns.DecisionCarbon = columns.DecisionCarbon || result.DecisionCarbon != nil
+	}
+	for _, names := range []*[]string{&columns.Models, &columns.Classes, &columns.Components, &columns.Regions, &columns.AccuracyConstraints} {
+		slices.Sort(*names)
+	}
+	return columns
+}
+
+// appendKeys appends the keys of values missing from names.
+func appendKeys[V any](names []string, values map[string]V) []string {
+	for name := range values {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Header returns the CSV columns. Keyed columns are suffixed with the model,
+// class, component, region or constraint they break the results down by.
+func (c CSVColumns) Header() []string {
+	header := []string{
+		"policy", "seed", "total_carbon_g", "slo_timeouts", "jobs_completed", "mean_accuracy",
+		"queueing_delay_s", "delayed_jobs", "pauses", "start_time", "end_time", "span_s",
+	}
+	if c.DecisionCarbon {
+		header = append(header, "decision_carbon_g")
+	}
+	for _, name := range c.Models {
+		header = append(header, "carbon_g_"+name, "slo_timeouts_"+name, "jobs_"+name)
+	}
+	for _, name := range c.Classes {
+		header = append(header, "slo_timeouts_class_"+name, "jobs_class_"+name)
+	}
+	for _, name := range c.Components {
+		header = append(header, "carbon_g_component_"+name, "slo_timeouts_component_"+name, "jobs_component_"+name)
+	}
+	for _, name := range c.Regions {
+		header = append(header, "carbon_g_region_"+name, "jobs_region_"+name)
+	}
+	for _, name := range c.AccuracyConstraints {
+		header = append(header, "accuracy_violations_"+name)
+	}
+	return header
+}
+
+// CSVRecord returns the results as a row matching columns.Header().
+func (r *Results) CSVRecord(columns CSVColumns) []string {
+	record := []string{
+		r.Policy,
+		strconv.FormatInt(r.Seed, 10),
+		strconv.FormatFloat(r.TotalCarbon, 'f', -1, 64),
+		strconv.Itoa(r.SLOTimeouts),
+		strconv.Itoa(r.JobsCompleted),
+		strconv.FormatFloat(r.MeanAccuracy, 'f', -1, 64),
+		strconv.FormatFloat(r.QueueingDelay, 'f', -1, 64),
+		strconv.Itoa(r.DelayedJobs),
+		strconv.Itoa(r.Pauses),
+		r.StartTime.Format(time.RFC3339),
+		r.EndTime.Format(time.RFC3339),
+		strconv.FormatFloat(r.Span, 'f', -1, 64),
+	}
+	if columns.DecisionCarbon {
+		decisionCarbon := ""
+		if r.DecisionCarbon != nil {
+			decisionCarbon = strconv.FormatFloat(*r.DecisionCarbon, 'f', -1, 64)
+		}
+		record = append(record, decisionCarbon)
+	}
+	for _, name := range columns.Models {
+		record = append(record,
+			strconv.FormatFloat(r.CarbonByModel[name], 'f', -1, 64),
+			strconv.Itoa(r.SLOTimeoutsByModel[name]),
+			strconv.Itoa(r.JobsByModel[name]),
+		)
+	}
+	for _, name := range columns.Classes {
+		record = append(record,
+			strconv.Itoa(r.SLOTimeoutsByClass[name]),
+			strconv.Itoa(r.JobsByClass[name]),
+		)
+	}
+	for _, name := range columns.Components {
+		record = append(record,
+			strconv.FormatFloat(r.CarbonByComponent[name], 'f', -1, 64),
+			strconv.Itoa(r.SLOTimeoutsByComponent[name]),
+			strconv.Itoa(r.JobsByComponent[name]),
+		)
+	}
+	for _, name := range columns.Regions {
+		record = append(record,
+			strconv.FormatFloat(r.CarbonByRegion[name], 'f', -1, 64),
+			strconv.Itoa(r.JobsByRegion[name]),
+		)
+	}
+	for _, name := range columns.AccuracyConstraints {
+		record = append(record, strconv.Itoa(r.AccuracyViolations[name]))
+	}
+	return record
+}
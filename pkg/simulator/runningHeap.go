@@ -22,10 +22,12 @@ func (pq *RunningHeap) Push(x any) {
 }
 
 func (pq *RunningHeap) Pop() any {
+	// container/heap swaps the minimum to the end before calling Pop
 	old := *pq
 	n := len(old)
-	x := old[0]
-	*pq = old[1:n]
+	x := old[n-1]
+	old[n-1] = nil
+	*pq = old[0 : n-1]
 	return x
 }
 
@@ -1,7 +1,10 @@
 package simulator
 
 import (
+	"io"
 	"simulator/pkg/directory"
+	"simulator/pkg/environment"
+	"simulator/pkg/simulator/policies"
 	"time"
 )
 
@@ -13,18 +16,41 @@ type SimulatorInterface interface {
 
 	// Public Methods
 	String() string
-	Begin() error
+	Begin() (*Results, error)
+	Results() *Results
+	SetEventLog(w io.Writer)
+	SetTrace(trace TraceWriter)
+	CarbonEmission() map[string]float64
+	TotalCarbon() float64
+	SLOTimeouts() map[string]int
+	TotalSLOTimeouts() int
 }
 
 type Simulator struct {
+	env      *environment.Environment
+	eventLog io.Writer   // Overrides the timestamped log file when set
+	trace    TraceWriter // Receives completed jobs when set
+
+	accuracyTarget      float64                              // Accuracy the constraints are checked against, 0 to skip
+	accuracyConstraints []func() policies.AccuracyConstraint // Create the constraints whose violations are reported
+
 	currTime       time.Time
+	firstEventTime time.Time
 	carbonEmission map[directory.AIModelDefinition]float64
 	sloTimeouts    map[directory.AIModelDefinition]int
+	queueingDelay  float64 // Total time jobs spent waiting for capacity, in seconds
+	delayedJobs    int     // Number of jobs that waited for capacity
+	pauses         int     // Number of times running jobs were suspended
+	decisionCarbon float64 // Carbon emitted in the signal policies optimise, in gCO2
+	signalsDiffer  bool    // Whether accounting used another signal than the policies
 
 	schedulingPolicy PolicyInterface
+	cluster          *Cluster            // Cluster of the home region
+	regionClusters   map[string]*Cluster // Clusters of the regions jobs may be routed to
 
 	incomingJobs         WorkloadQueue // Jobs as they enter
 	queuedJobs           AwaitingHeap  // Jobs that are queued
+	blockedJobs          WorkloadQueue // Jobs whose start time passed while their cluster was full
 	currentlyRunningJobs RunningHeap   // Jobs that are currently running
 	completedJobs        WorkloadQueue // Jobs that have completed
 }
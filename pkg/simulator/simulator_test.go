@@ -0,0 +1,320 @@
+package simulator
+
+import (
+	"io"
+	"log"
+	"math"
+	"simulator/pkg/directory"
+	"simulator/pkg/environment"
+	"simulator/pkg/simulator/policies"
+	"simulator/pkg/synthetic"
+	"simulator/pkg/workload"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	smallModel = directory.AIModelDefinition{ModelName: "small", MeanRunTime: 600, EnergyUsage: 5, Accuracy: 0.5, Slots: 1}
+	largeModel = directory.AIModelDefinition{ModelName: "large", MeanRunTime: 600, EnergyUsage: 20, Accuracy: 0.9, Slots: 2}
+)
+
+// recordingPolicy runs every job straight away for its model's mean runtime
+// and records the jobs in the order they start.
+type recordingPolicy struct {
+	started []*workload.Job
+}
+
+func (p *recordingPolicy) HandleIncoming(job *workload.Job) error {
+	job.EndTime = job.StartTime.Add(time.Duration(job.Model.MeanRunTime) * time.Second)
+	job.DueTime = job.EndTime
+	return nil
+}
+
+func (p *recordingPolicy) HandleQueued(job *workload.Job) error {
+	p.started = append(p.started, job)
+	return nil
+}
+
+func (p *recordingPolicy) HandleRunning(job *workload.Job) error {
+	return nil
+}
+
+func (p *recordingPolicy) String() string {
+	return "recording"
+}
+
+// testEnvironment returns an environment over a day of synthetic data.
+func testEnvironment(t *testing.T) *environment.Environment {
+	t.Helper()
+	model := synthetic.Default()
+	model.Duration = 24 * time.Hour
+	dataLoader, err := synthetic.NewLoader(model, 1)
+	if err != nil {
+		t.Fatalf("generating trace: %v", err)
+	}
+	return &environment.Environment{Loader: dataLoader, Logger: log.New(io.Discard, "", 0)}
+}
+
+// arrival is a job of the model arriving after the start of the data.
+type arrival struct {
+	after time.Duration
+	model *directory.AIModelDefinition
+}
+
+func TestAdmission(t *testing.T) {
+	tests := []struct {
+		name      string
+		capacity  int
+		arrivals  []arrival
+		wantOrder []int           // Indices of the arrivals in the order they start
+		wantStart []time.Duration // Start of each job in wantOrder
+		wantDelay time.Duration   // Total queueing delay
+	}{
+		{
+			name:      "unbounded",
+			arrivals:  []arrival{{0, &largeModel}, {time.Minute, &largeModel}, {2 * time.Minute, &largeModel}},
+			wantOrder: []int{0, 1, 2},
+			wantStart: []time.Duration{0, time.Minute, 2 * time.Minute},
+		},
+		{
+			name:      "below capacity",
+			capacity:  4,
+			arrivals:  []arrival{{0, &largeModel}, {time.Minute, &largeModel}},
+			wantOrder: []int{0, 1},
+			wantStart: []time.Duration{0, time.Minute},
+		},
+		{
+			name:      "at capacity",
+			capacity:  2,
+			arrivals:  []arrival{{0, &smallModel}, {time.Minute, &smallModel}, {2 * time.Minute, &smallModel}, {3 * time.Minute, &smallModel}},
+			wantOrder: []int{0, 1, 2, 3},
+			wantStart: []time.Duration{0, time.Minute, 10 * time.Minute, 11 * time.Minute},
+			wantDelay: 16 * time.Minute,
+		},
+		{
+			name:      "released in arrival order",
+			capacity:  1,
+			arrivals:  []arrival{{0, &smallModel}, {time.Minute, &smallModel}, {2 * time.Minute, &smallModel}, {3 * time.Minute, &smallModel}},
+			wantOrder: []int{0, 1, 2, 3},
+			wantStart: []time.Duration{0, 10 * time.Minute, 20 * time.Minute, 30 * time.Minute},
+			wantDelay: 9*time.Minute + 18*time.Minute + 27*time.Minute,
+		},
+		{
+			name:      "small job waits behind a blocked large one",
+			capacity:  3,
+			arrivals:  []arrival{{0, &largeModel}, {time.Minute, &largeModel}, {2 * time.Minute, &smallModel}},
+			wantOrder: []int{0, 1, 2},
+			wantStart: []time.Duration{0, 10 * time.Minute, 10 * time.Minute},
+			wantDelay: 9*time.Minute + 8*time.Minute,
+		},
+		{
+			name:      "freed slots start several jobs",
+			capacity:  2,
+			arrivals:  []arrival{{0, &largeModel}, {time.Minute, &smallModel}, {2 * time.Minute, &smallModel}, {3 * time.Minute, &largeModel}},
+			wantOrder: []int{0, 1, 2, 3},
+			wantStart: []time.Duration{0, 10 * time.Minute, 10 * time.Minute, 20 * time.Minute},
+			wantDelay: 9*time.Minute + 8*time.Minute + 17*time.Minute,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := testEnvironment(t)
+			jobs := make([]*workload.Job, len(test.arrivals))
+			for i, arrival := range test.arrivals {
+				jobs[i] = &workload.Job{Model: arrival.model, StartTime: env.Loader.StartDate().Add(arrival.after)}
+			}
+			arrived := slices.Clone(jobs)
+			policy := &recordingPolicy{}
+			s := NewSimulator(env, jobs, policy, NewCluster(test.capacity, nil))
+			s.SetEventLog(io.Discard)
+			results, err := s.Begin()
+			if err != nil {
+				t.Fatalf("Begin: %v", err)
+			}
+			if results.JobsCompleted != len(test.arrivals) {
+				t.Fatalf("%d jobs completed, want %d", results.JobsCompleted, len(test.arrivals))
+			}
+			order := make([]int, len(policy.started))
+			for i, job := range policy.started {
+				order[i] = slices.Index(arrived, job)
+			}
+			if !slices.Equal(order, test.wantOrder) {
+				t.Fatalf("jobs started in the order %v, want %v", order, test.wantOrder)
+			}
+			for i, job := range policy.started {
+				if want := env.Loader.StartDate().Add(test.wantStart[i]); !job.StartTime.Equal(want) {
+					t.Errorf("job %d started at %v, want %v", order[i], job.StartTime, want)
+				}
+				if runtime := job.EndTime.Sub(job.StartTime); runtime != 10*time.Minute {
+					t.Errorf("job %d ran for %v, want its full runtime", order[i], runtime)
+				}
+			}
+			if results.QueueingDelay != test.wantDelay.Seconds() {
+				t.Errorf("queueing delay %fs, want %fs", results.QueueingDelay, test.wantDelay.Seconds())
+			}
+			delayed := 0
+			for _, job := range arrived {
+				if job.QueueDelay > 0 {
+					delayed++
+				}
+			}
+			if results.DelayedJobs != delayed {
+				t.Errorf("%d delayed jobs reported, %d waited", results.DelayedJobs, delayed)
+			}
+		})
+	}
+}
+
+func TestAdmissionAboveCapacity(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		slots    map[string]int
+		model    *directory.AIModelDefinition
+	}{
+		{name: "override above capacity", capacity: 2, slots: map[string]int{"small": 3}, model: &smallModel},
+		{name: "model above capacity", capacity: 1, model: &largeModel},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := testEnvironment(t)
+			jobs := []*workload.Job{{Model: test.model, StartTime: env.Loader.StartDate()}}
+			s := NewSimulator(env, jobs, &recordingPolicy{}, NewCluster(test.capacity, test.slots))
+			s.SetEventLog(io.Discard)
+			if _, err := s.Begin(); err == nil || !strings.Contains(err.Error(), test.model.ModelName) {
+				t.Errorf("Begin error = %v, want one naming %s", err, test.model.ModelName)
+			}
+		})
+	}
+}
+
+func TestAdmitBlocked(t *testing.T) {
+	tests := []struct {
+		name         string
+		homeCapacity int
+		blocked      []string // Regions of the blocked jobs, in arrival order
+		wantStarted  []string
+		wantWaiting  []string
+	}{
+		{
+			name:         "home cluster full",
+			homeCapacity: 1,
+			blocked:      []string{"", "elsewhere", "west"},
+			wantStarted:  []string{"west"},
+			wantWaiting:  []string{"", "elsewhere"},
+		},
+		{
+			name:         "room for one at home",
+			homeCapacity: 2,
+			blocked:      []string{"", "elsewhere", "west", "west"},
+			wantStarted:  []string{"", "west"},
+			wantWaiting:  []string{"elsewhere", "west"},
+		},
+		{
+			name:         "unknown regions share the home line",
+			homeCapacity: 2,
+			blocked:      []string{"elsewhere", "", "west"},
+			wantStarted:  []string{"elsewhere", "west"},
+			wantWaiting:  []string{""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := testEnvironment(t)
+			env.Regions = []environment.Region{{Name: "west", Loader: env.Loader, Capacity: 1}}
+			policy := &recordingPolicy{}
+			s := NewSimulator(env, nil, policy, NewCluster(test.homeCapacity, nil))
+			// One job holds a slot of the home cluster
+			if err := s.cluster.Acquire(&smallModel); err != nil {
+				t.Fatalf("Acquire: %v", err)
+			}
+			for _, region := range test.blocked {
+				s.blockedJobs.Push(&workload.Job{Model: &smallModel, StartTime: s.currTime, Region: region})
+			}
+			if err := s.admitBlocked(); err != nil {
+				t.Fatalf("admitBlocked: %v", err)
+			}
+			regions := func(jobs []*workload.Job) []string {
+				names := make([]string, len(jobs))
+				for i, job := range jobs {
+					names[i] = job.Region
+				}
+				return names
+			}
+			if got := regions(policy.started); !slices.Equal(got, test.wantStarted) {
+				t.Errorf("started jobs in %q, want %q", got, test.wantStarted)
+			}
+			if got := regions(s.blockedJobs); !slices.Equal(got, test.wantWaiting) {
+				t.Errorf("jobs in %q still waiting, want %q", got, test.wantWaiting)
+			}
+		})
+	}
+}
+
+func TestAccuracyViolations(t *testing.T) {
+	env := testEnvironment(t)
+	jobs := make([]*workload.Job, 0)
+	for i, model := range []*directory.AIModelDefinition{&smallModel, &largeModel, &smallModel, &smallModel} {
+		jobs = append(jobs, &workload.Job{Model: model, StartTime: env.Loader.StartDate().Add(time.Duration(i) * time.Minute)})
+	}
+	s := NewSimulator(env, jobs, &recordingPolicy{}, nil)
+	s.SetEventLog(io.Discard)
+	if err := s.SetAccuracyConstraints(0.7, []string{"global", "job", "window:2", "nearby"}); err == nil {
+		t.Fatalf("SetAccuracyConstraints accepted an unknown constraint")
+	}
+	if err := s.SetAccuracyConstraints(0.7, []string{"global", "job", "window:2"}); err != nil {
+		t.Fatalf("SetAccuracyConstraints: %v", err)
+	}
+	results, err := s.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	want := map[string]int{"global": 3, "job": 3, "window:2": 2}
+	for _, violations := range []map[string]int{results.AccuracyViolations, s.Results().AccuracyViolations} {
+		if len(violations) != len(want) {
+			t.Fatalf("violations %v, want %v", violations, want)
+		}
+		for kind, count := range want {
+			if violations[kind] != count {
+				t.Errorf("%d violations of %s, want %d", violations[kind], kind, count)
+			}
+		}
+	}
+}
+
+func TestDecisionCarbonIncludesTransfer(t *testing.T) {
+	env := testEnvironment(t)
+	model := synthetic.Default()
+	model.Duration = 24 * time.Hour
+	accounting, err := synthetic.NewLoader(model, 2)
+	if err != nil {
+		t.Fatalf("generating trace: %v", err)
+	}
+	env.Regions = []environment.Region{{Name: "west", Loader: env.Loader, Accounting: accounting, TransferCarbon: 50}}
+	start := env.Loader.StartDate().Add(time.Hour)
+	jobs := []*workload.Job{
+		{Model: &smallModel, StartTime: start, Region: "west"},
+		{Model: &largeModel, StartTime: start.Add(time.Hour)},
+	}
+	s := NewSimulator(env, jobs, &recordingPolicy{}, nil)
+	s.SetEventLog(io.Discard)
+	results, err := s.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if results.DecisionCarbon == nil {
+		t.Fatalf("no decision carbon for a run accounting in another signal")
+	}
+	end := start.Add(10 * time.Minute)
+	routedAccounting := policies.CarbonCalculate(accounting, start, end, &smallModel) + 50
+	routedDecision := policies.CarbonCalculate(env.Loader, start, end, &smallModel) + 50
+	home := policies.CarbonCalculate(env.Loader, start.Add(time.Hour), end.Add(time.Hour), &largeModel)
+	if want := routedAccounting + home; math.Abs(results.TotalCarbon-want) > 1e-9 {
+		t.Errorf("total carbon %f, want %f", results.TotalCarbon, want)
+	}
+	if want := routedDecision + home; math.Abs(*results.DecisionCarbon-want) > 1e-9 {
+		t.Errorf("decision carbon %f, want %f", *results.DecisionCarbon, want)
+	}
+}
@@ -0,0 +1,137 @@
+package simulator
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"simulator/pkg/workload"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceWriter receives every job as it completes.
+type TraceWriter interface {
+	WriteJob(job *workload.Job) error
+	Flush() error
+}
+
+// TraceRecord is the exported view of a completed job.
+type TraceRecord struct {
+	ArrivalTime    time.Time          `json:"arrival_time"`
+	Model          string             `json:"model"`
+	ScheduledStart time.Time          `json:"scheduled_start"`
+	ActualStart    time.Time          `json:"actual_start"`
+	EndTime        time.Time          `json:"end_time"`
+	DueTime        time.Time          `json:"due_time"`
+	QueueDelay     float64            `json:"queue_delay_s"` // in seconds
+	Carbon         float64            `json:"carbon_g"`      // in gCO2
+	SLOMet         bool               `json:"slo_met"`
+	SLOClass       string             `json:"slo_class,omitempty"`
+	Tenant         string             `json:"tenant,omitempty"`
+	Component      string             `json:"component,omitempty"`
+	Region         string             `json:"region,omitempty"`
+	Decision       map[string]string  `json:"decision,omitempty"`
+	Segments       []workload.Segment `json:"segments,omitempty"` // Only for jobs that were suspended
+}
+
+func NewTraceRecord(job *workload.Job) TraceRecord {
+	record := TraceRecord{
+		ArrivalTime:    job.ArrivalTime,
+		Model:          job.Model.ModelName,
+		ScheduledStart: job.ScheduledStart,
+		ActualStart:    job.StartTime,
+		EndTime:        job.EndTime,
+		DueTime:        job.DueTime,
+		QueueDelay:     job.QueueDelay.Seconds(),
+		Carbon:         job.Carbon,
+		SLOMet:         !job.DueTime.Before(job.EndTime),
+		SLOClass:       job.SLOClass,
+		Tenant:         job.Tenant,
+		Component:      job.Component,
+		Region:         job.Region,
+		Decision:       job.Decision,
+	}
+	if len(job.Segments) > 0 {
+		record.ActualStart = job.Segments[0].Start
+	}
+	if len(job.Segments) > 1 {
+		record.Segments = job.Segments
+	}
+	return record
+}
+
+type CSVTraceWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func NewCSVTraceWriter(w io.Writer) *CSVTraceWriter {
+	return &CSVTraceWriter{
+		writer: csv.NewWriter(w),
+	}
+}
+
+var traceCSVHeader = []string{
+	"arrival_time", "model", "scheduled_start", "actual_start", "end_time", "due_time",
+	"queue_delay_s", "carbon_g", "slo_met", "slo_class", "tenant", "component", "region", "segments", "decision",
+}
+
+func (c *CSVTraceWriter) WriteJob(job *workload.Job) error {
+	if !c.headerWritten {
+		if err := c.writer.Write(traceCSVHeader); err != nil {
+			return fmt.Errorf("error writing trace header: %w", err)
+		}
+		c.headerWritten = true
+	}
+	record := NewTraceRecord(job)
+	// Decision metadata is flattened into key=value pairs in a fixed order
+	notes := make([]string, 0, len(record.Decision))
+	for _, key := range slices.Sorted(maps.Keys(record.Decision)) {
+		notes = append(notes, key+"="+record.Decision[key])
+	}
+	return c.writer.Write([]string{
+		record.ArrivalTime.Format(time.RFC3339Nano),
+		record.Model,
+		record.ScheduledStart.Format(time.RFC3339Nano),
+		record.ActualStart.Format(time.RFC3339Nano),
+		record.EndTime.Format(time.RFC3339Nano),
+		record.DueTime.Format(time.RFC3339Nano),
+		strconv.FormatFloat(record.QueueDelay, 'f', -1, 64),
+		strconv.FormatFloat(record.Carbon, 'f', -1, 64),
+		strconv.FormatBool(record.SLOMet),
+		record.SLOClass,
+		record.Tenant,
+		record.Component,
+		record.Region,
+		strconv.Itoa(max(len(record.Segments), 1)),
+		strings.Join(notes, ";"),
+	})
+}
+
+func (c *CSVTraceWriter) Flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// JSONLTraceWriter writes one JSON object per line.
+type JSONLTraceWriter struct {
+	encoder *json.Encoder
+}
+
+func NewJSONLTraceWriter(w io.Writer) *JSONLTraceWriter {
+	return &JSONLTraceWriter{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+func (j *JSONLTraceWriter) WriteJob(job *workload.Job) error {
+	return j.encoder.Encode(NewTraceRecord(job))
+}
+
+func (j *JSONLTraceWriter) Flush() error {
+	return nil
+}
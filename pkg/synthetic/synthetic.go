@@ -0,0 +1,150 @@
+package synthetic
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"simulator/pkg/loader"
+	"strconv"
+	"time"
+)
+
+// Default returns a model shaped like a grid with plenty of solar: four weeks
+// of data every five minutes with a pronounced duck curve.
+func Default() Model {
+	return Model{
+		Start:       time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Duration:    28 * 24 * time.Hour,
+		Interval:    loader.DefaultInterval,
+		Location:    time.UTC,
+		Base:        250,
+		Diurnal:     0.1,
+		DiurnalPeak: 19,
+		Weekly:      0.05,
+		Solar:       0.35,
+		Sunrise:     6,
+		Sunset:      18,
+		Ramp:        0.15,
+		Noise:       0.03,
+		Correlation: 0.9,
+		RegimeRate:  1.0 / 7,
+		RegimeShift: 0.1,
+	}
+}
+
+// Validate checks the model can generate a trace.
+func (m Model) Validate() error {
+	switch {
+	case m.Duration <= 0:
+		return fmt.Errorf("duration must be positive, got %v", m.Duration)
+	case m.Interval <= 0:
+		return fmt.Errorf("interval must be positive, got %v", m.Interval)
+	case m.Base < 0:
+		return fmt.Errorf("base intensity must not be negative, got %f", m.Base)
+	case m.DiurnalPeak < 0 || m.DiurnalPeak >= 24:
+		return fmt.Errorf("diurnal peak must be an hour between 0 and 24, got %f", m.DiurnalPeak)
+	case m.Weekly < 0 || m.Weekly > 1:
+		return fmt.Errorf("weekly drop must be between 0 and 1, got %f", m.Weekly)
+	case m.Solar < 0 || m.Ramp < 0:
+		return fmt.Errorf("solar trough and evening ramp must not be negative")
+	case m.Sunrise < 0 || m.Sunset > 24 || m.Sunrise >= m.Sunset:
+		return fmt.Errorf("sunrise and sunset must be hours with 0 <= sunrise < sunset <= 24, got %f and %f", m.Sunrise, m.Sunset)
+	case m.Noise < 0:
+		return fmt.Errorf("noise must not be negative, got %f", m.Noise)
+	case m.Correlation < 0 || m.Correlation >= 1:
+		return fmt.Errorf("noise correlation must be in [0, 1), got %f", m.Correlation)
+	case m.RegimeRate < 0 || m.RegimeShift < 0:
+		return fmt.Errorf("regime rate and shift must not be negative")
+	}
+	return nil
+}
+
+func (m Model) String() string {
+	return fmt.Sprintf("synthetic trace of %v every %v from %s, base %f kgCO2/MWh", m.Duration, m.Interval, m.Start.Format(time.RFC3339), m.Base)
+}
+
+// Generate draws a trace from the model. Every random draw comes from a
+// generator seeded with seed, so a seed always gives the same trace.
+func Generate(m Model, seed int64) ([]*loader.DataPoint, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	location := m.Location
+	if location == nil {
+		location = time.UTC
+	}
+	r := rand.New(rand.NewSource(seed))
+	innovation := math.Sqrt(1 - m.Correlation*m.Correlation)
+	shiftChance := m.RegimeRate * m.Interval.Hours() / 24
+	noise, level := r.NormFloat64(), 1.0
+	entries := int((m.Duration + m.Interval - 1) / m.Interval)
+	data := make([]*loader.DataPoint, entries)
+	for i := range data {
+		date := m.Start.Add(time.Duration(i) * m.Interval)
+		if i > 0 {
+			noise = m.Correlation*noise + innovation*r.NormFloat64()
+			if r.Float64() < shiftChance {
+				level = max(1+m.RegimeShift*r.NormFloat64(), 0)
+			}
+		}
+		intensity := m.Base * level * m.shape(date.In(location)) * (1 + m.Noise*noise)
+		data[i] = &loader.DataPoint{StartDate: date, CarbonIntensity: max(intensity, 0)}
+	}
+	return data, nil
+}
+
+// shape returns the seasonal multiplier of the base intensity at local.
+func (m Model) shape(local time.Time) float64 {
+	hour := float64(local.Hour()) + float64(local.Minute())/60 + float64(local.Second())/3600
+	factor := 1 + m.Diurnal*math.Cos(2*math.Pi*(hour-m.DiurnalPeak)/24)
+	if hour > m.Sunrise && hour < m.Sunset {
+		// Solar output follows a half sine between sunrise and sunset
+		daylight := math.Sin(math.Pi * (hour - m.Sunrise) / (m.Sunset - m.Sunrise))
+		factor -= m.Solar * daylight * daylight
+	}
+	// The ramp peaks an hour after sunset and fades over the following hours
+	sinceSunset := hour - m.Sunset - 1
+	factor += m.Ramp * math.Exp(-sinceSunset*sinceSunset/2)
+	if weekday := local.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+		factor *= 1 - m.Weekly
+	}
+	return max(factor, 0)
+}
+
+// NewLoader generates a trace and wraps it in a loader, standing in for a
+// data file wherever one is needed.
+func NewLoader(m Model, seed int64) (*loader.Loader, error) {
+	data, err := Generate(m, seed)
+	if err != nil {
+		return nil, err
+	}
+	location := m.Location
+	if location == nil {
+		location = time.UTC
+	}
+	dataLoader := loader.NewLoaderFromData(fmt.Sprintf("%s (seed %d)", m, seed), data, location)
+	dataLoader.SetInterval(m.Interval)
+	return dataLoader, nil
+}
+
+// WriteCSV writes a trace in the layout of data/collected, which the loader
+// reads with its default schema.
+func WriteCSV(w io.Writer, data []*loader.DataPoint) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"start_date", "generated_rate_kg_per_mwh"}); err != nil {
+		return fmt.Errorf("error writing carbon data header: %w", err)
+	}
+	for _, dataPoint := range data {
+		record := []string{
+			dataPoint.StartDate.UTC().Format(time.RFC3339),
+			strconv.FormatFloat(dataPoint.CarbonIntensity, 'f', -1, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("error writing carbon data record: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,33 @@
+package synthetic
+
+import "time"
+
+// Model describes a synthetic carbon intensity trace. Each component scales
+// the base intensity, so amplitudes are fractions of Base. Hours are read in
+// Location, the trace's region.
+type Model struct {
+	Start    time.Time      // Start of the first interval
+	Duration time.Duration  // Length of the trace
+	Interval time.Duration  // Sampling interval
+	Location *time.Location // Zone hours and weekdays are read in, UTC when nil
+
+	Base        float64 // Mean intensity in kgCO2/MWh
+	Diurnal     float64 // Relative amplitude of the daily cycle
+	DiurnalPeak float64 // Local hour the daily cycle peaks at
+	Weekly      float64 // Relative drop in intensity on Saturdays and Sundays
+
+	// Duck curve: solar generation deepens a midday trough between sunrise
+	// and sunset, followed by a steep evening ramp as it falls away
+	Solar   float64 // Relative depth of the midday trough
+	Sunrise float64 // Local hour solar generation starts
+	Sunset  float64 // Local hour solar generation ends
+	Ramp    float64 // Relative height of the evening peak just after sunset
+
+	Noise       float64 // Relative standard deviation of the noise
+	Correlation float64 // AR(1) correlation between the noise of consecutive intervals
+
+	// Regime shifts, such as outages or changes in fuel prices, move the
+	// level of the whole trace until the next shift
+	RegimeRate  float64 // Expected shifts per day
+	RegimeShift float64 // Relative standard deviation of the level after a shift
+}
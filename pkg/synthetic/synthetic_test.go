@@ -0,0 +1,204 @@
+package synthetic
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// flat returns a noise-free model of two days every hour whose intensity is
+// Base throughout, for the tests to add a single component to.
+func flat() Model {
+	return Model{
+		Start:    time.Date(2023, time.January, 2, 0, 0, 0, 0, time.UTC), // A Monday
+		Duration: 48 * time.Hour,
+		Interval: time.Hour,
+		Base:     200,
+		Sunrise:  6,
+		Sunset:   18,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(m *Model)
+		wantErr bool
+	}{
+		{name: "default", change: func(m *Model) {}},
+		{name: "no noise or regime shifts", change: func(m *Model) { m.Noise, m.RegimeRate, m.RegimeShift = 0, 0, 0 }},
+		{name: "daylight all day", change: func(m *Model) { m.Sunrise, m.Sunset = 0, 24 }},
+		{name: "zero duration", change: func(m *Model) { m.Duration = 0 }, wantErr: true},
+		{name: "negative interval", change: func(m *Model) { m.Interval = -time.Minute }, wantErr: true},
+		{name: "negative base", change: func(m *Model) { m.Base = -1 }, wantErr: true},
+		{name: "diurnal peak past midnight", change: func(m *Model) { m.DiurnalPeak = 24 }, wantErr: true},
+		{name: "weekly drop above one", change: func(m *Model) { m.Weekly = 1.5 }, wantErr: true},
+		{name: "negative solar trough", change: func(m *Model) { m.Solar = -0.1 }, wantErr: true},
+		{name: "negative ramp", change: func(m *Model) { m.Ramp = -0.1 }, wantErr: true},
+		{name: "sunset before sunrise", change: func(m *Model) { m.Sunrise, m.Sunset = 18, 6 }, wantErr: true},
+		{name: "sunset past midnight", change: func(m *Model) { m.Sunset = 25 }, wantErr: true},
+		{name: "negative noise", change: func(m *Model) { m.Noise = -0.01 }, wantErr: true},
+		{name: "noise correlation of one", change: func(m *Model) { m.Correlation = 1 }, wantErr: true},
+		{name: "negative regime rate", change: func(m *Model) { m.RegimeRate = -1 }, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := Default()
+			test.change(&m)
+			err := m.Validate()
+			if (err != nil) != test.wantErr {
+				t.Errorf("Validate() = %v, want an error: %v", err, test.wantErr)
+			}
+			if _, genErr := Generate(m, 1); (genErr != nil) != test.wantErr {
+				t.Errorf("Generate error = %v, want an error: %v", genErr, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateShape(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(m *Model)
+		hour   int     // Hour from the start of the trace
+		want   float64 // Multiple of Base at that hour
+	}{
+		{name: "flat", change: func(m *Model) {}, hour: 13, want: 1},
+		{name: "diurnal peak", change: func(m *Model) { m.Diurnal, m.DiurnalPeak = 0.2, 19 }, hour: 19, want: 1.2},
+		{name: "diurnal trough", change: func(m *Model) { m.Diurnal, m.DiurnalPeak = 0.2, 19 }, hour: 7, want: 0.8},
+		{name: "solar noon", change: func(m *Model) { m.Solar = 0.4 }, hour: 12, want: 0.6},
+		{name: "no solar at night", change: func(m *Model) { m.Solar = 0.4 }, hour: 3, want: 1},
+		{name: "evening ramp peak", change: func(m *Model) { m.Ramp = 0.3 }, hour: 19, want: 1.3},
+		{name: "weekday", change: func(m *Model) { m.Weekly = 0.1 }, hour: 30, want: 1},
+		{name: "Sunday in local time", change: func(m *Model) {
+			m.Weekly = 0.1
+			m.Location = time.FixedZone("UTC-2", -2*60*60)
+		}, hour: 1, want: 0.9},
+		{name: "diurnal peak in local time", change: func(m *Model) {
+			m.Diurnal, m.DiurnalPeak = 0.2, 19
+			m.Location = time.FixedZone("UTC+3", 3*60*60)
+		}, hour: 16, want: 1.2},
+		{name: "trough clipped at zero", change: func(m *Model) { m.Solar = 2 }, hour: 12, want: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := flat()
+			test.change(&m)
+			data, err := Generate(m, 1)
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if got, want := data[test.hour].CarbonIntensity, m.Base*test.want; math.Abs(got-want) > 1e-9 {
+				t.Errorf("intensity at hour %d is %f, want %f", test.hour, got, want)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	m := Default()
+	m.Duration = 7*24*time.Hour + time.Minute // Not a whole number of intervals
+	m.RegimeRate = 2
+	data, err := Generate(m, 7)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if want := 7*24*12 + 1; len(data) != want {
+		t.Fatalf("generated %d entries, want %d", len(data), want)
+	}
+	total := 0.0
+	for i, dataPoint := range data {
+		if want := m.Start.Add(time.Duration(i) * m.Interval); !dataPoint.StartDate.Equal(want) {
+			t.Fatalf("entry %d starts at %v, want %v", i, dataPoint.StartDate, want)
+		}
+		if dataPoint.CarbonIntensity < 0 {
+			t.Fatalf("entry %d has negative intensity %f", i, dataPoint.CarbonIntensity)
+		}
+		total += dataPoint.CarbonIntensity
+	}
+	if mean := total / float64(len(data)); mean < 0.5*m.Base || mean > 1.5*m.Base {
+		t.Errorf("mean intensity %f is far from the base %f", mean, m.Base)
+	}
+
+	again, err := Generate(m, 7)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	other, err := Generate(m, 8)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	differs := false
+	for i := range data {
+		if again[i].CarbonIntensity != data[i].CarbonIntensity {
+			t.Fatalf("entry %d differs between runs with the same seed", i)
+		}
+		differs = differs || other[i].CarbonIntensity != data[i].CarbonIntensity
+	}
+	if !differs {
+		t.Errorf("different seeds generated the same trace")
+	}
+}
+
+func TestNewLoader(t *testing.T) {
+	m := Default()
+	m.Duration = 24 * time.Hour
+	m.Interval = 15 * time.Minute
+	dataLoader, err := NewLoader(m, 1)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	if dataLoader.NumEntries() != 96 || dataLoader.Interval() != m.Interval {
+		t.Errorf("loader holds %d entries every %v, want 96 every %v", dataLoader.NumEntries(), dataLoader.Interval(), m.Interval)
+	}
+	if !dataLoader.StartDate().Equal(m.Start) || !dataLoader.EndDate().Equal(m.Start.Add(m.Duration)) {
+		t.Errorf("loader covers %v to %v, want %v to %v", dataLoader.StartDate(), dataLoader.EndDate(), m.Start, m.Start.Add(m.Duration))
+	}
+	m.Interval = 0
+	if _, err := NewLoader(m, 1); err == nil {
+		t.Errorf("NewLoader with an invalid model succeeded, want an error")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	m := Default()
+	m.Duration = 6 * time.Hour
+	m.Location = time.FixedZone("UTC+9", 9*60*60)
+	data, err := Generate(m, 3)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	var written bytes.Buffer
+	if err := WriteCSV(&written, data); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	records, err := csv.NewReader(&written).ReadAll()
+	if err != nil {
+		t.Fatalf("reading written CSV: %v", err)
+	}
+	if len(records) != len(data)+1 {
+		t.Fatalf("wrote %d records, want a header and %d rows", len(records), len(data))
+	}
+	if header := records[0]; len(header) != 2 || header[0] != "start_date" || header[1] != "generated_rate_kg_per_mwh" {
+		t.Errorf("header is %v", header)
+	}
+	for i, record := range records[1:] {
+		date, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			t.Fatalf("row %d: %v", i, err)
+		}
+		if date.Location() != time.UTC || !date.Equal(data[i].StartDate) {
+			t.Errorf("row %d starts at %s, want %v in UTC", i, record[0], data[i].StartDate)
+		}
+		intensity, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			t.Fatalf("row %d: %v", i, err)
+		}
+		if intensity != data[i].CarbonIntensity {
+			t.Errorf("row %d has intensity %f, want %f", i, intensity, data[i].CarbonIntensity)
+		}
+	}
+}
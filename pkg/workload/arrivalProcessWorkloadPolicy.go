@@ -0,0 +1,249 @@
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+	"simulator/pkg/environment"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PoissonWorkload generates arrivals from a homogeneous Poisson process.
+type PoissonWorkload struct {
+	Rate float64 // Mean arrivals per hour
+}
+
+// NHPPWorkload generates arrivals from a non-homogeneous Poisson process whose
+// rate follows an hourly (24 entries) or weekly (168 entries, starting Monday
+// at midnight) profile of multipliers in local time.
+type NHPPWorkload struct {
+	Rate     float64        // Arrivals per hour at a multiplier of 1
+	Profile  []float64      // Rate multiplier for each hour of the day or week
+	Location *time.Location // Zone the profile hours are in, UTC when nil
+}
+
+// MMPPState is one state of a Markov-modulated Poisson process.
+type MMPPState struct {
+	Rate      float64       // Mean arrivals per hour while in the state
+	MeanDwell time.Duration // Mean time spent in the state before switching
+}
+
+// MMPPWorkload generates bursty arrivals from a Markov-modulated Poisson
+// process. The process starts in the first state and, on leaving a state,
+// moves to one of the others with equal probability.
+type MMPPWorkload struct {
+	States []MMPPState
+}
+
+func NewPoissonWorkload(rate float64) *PoissonWorkload {
+	return &PoissonWorkload{
+		Rate: rate,
+	}
+}
+
+func NewNHPPWorkload(rate float64, profile []float64, location *time.Location) *NHPPWorkload {
+	return &NHPPWorkload{
+		Rate:     rate,
+		Profile:  profile,
+		Location: location,
+	}
+}
+
+func NewMMPPWorkload(states []MMPPState) *MMPPWorkload {
+	return &MMPPWorkload{
+		States: states,
+	}
+}
+
+// ParseMMPPStates parses comma separated rate:dwell pairs such as
+// "20:2h,200:15m", giving arrivals per hour and the mean time in each state.
+func ParseMMPPStates(spec string) ([]MMPPState, error) {
+	entries := strings.Split(spec, ",")
+	if len(entries) < 2 {
+		return nil, fmt.Errorf("a Markov-modulated process needs at least two rate:dwell states, got %q", spec)
+	}
+	states := make([]MMPPState, len(entries))
+	for i, entry := range entries {
+		rate, dwell, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid state %q, please use rate:dwell such as 20:2h", entry)
+		}
+		parsedRate, err := strconv.ParseFloat(rate, 64)
+		if err != nil || parsedRate < 0 {
+			return nil, fmt.Errorf("invalid rate %q in state %q", rate, entry)
+		}
+		parsedDwell, err := time.ParseDuration(dwell)
+		if err != nil || parsedDwell <= 0 {
+			return nil, fmt.Errorf("invalid dwell time %q in state %q", dwell, entry)
+		}
+		states[i] = MMPPState{Rate: parsedRate, MeanDwell: parsedDwell}
+	}
+	return states, nil
+}
+
+// arrivalWindow returns the span arrivals may fall in, leaving room for the
+// longest deadline before the end of the data.
+func arrivalWindow(env *environment.Environment, jobInfo JobMetadata) (time.Time, time.Time, error) {
+	loader := env.Loader
+	if loader == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("loader is not initialized")
+	}
+	if loader.NumEntries() == 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("no data available in loader")
+	}
+	startDate := loader.StartDate()
+	endDate := loader.EndDate().Add(-jobInfo.DueTime)
+	if !endDate.After(startDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid loader date range")
+	}
+	return startDate, endDate, nil
+}
+
+// exponentialGap draws the time to the next arrival of a process with the
+// given rate in arrivals per hour.
+func exponentialGap(r *rand.Rand, rate float64) time.Duration {
+	return time.Duration(r.ExpFloat64() / rate * float64(time.Hour))
+}
+
+// collectArrivals turns arrival times into jobs until the window ends or the
+// job limit is reached. next returns the following arrival time.
+func collectArrivals(env *environment.Environment, jobInfo JobMetadata, endDate time.Time, next func() time.Time) ([]*Job, error) {
+	jobList := make([]*Job, 0)
+	for jobInfo.NumJobs <= 0 || len(jobList) < jobInfo.NumJobs {
+		startTime := next()
+		if startTime.After(endDate) {
+			break
+		}
+		jobList = append(jobList, jobInfo.newJob(env.Rand, startTime))
+	}
+	if len(jobList) == 0 {
+		return nil, fmt.Errorf("no jobs to generate, the arrival rate is too low for the data range")
+	}
+	return jobList, nil
+}
+
+// GenerateWorkload generates Poisson arrivals across the loader's data range,
+// stopping early once jobInfo.NumJobs jobs have arrived.
+func (pw *PoissonWorkload) GenerateWorkload(env *environment.Environment, jobInfo JobMetadata) ([]*Job, error) {
+	if pw.Rate <= 0 {
+		return nil, fmt.Errorf("arrival rate must be positive, got %f", pw.Rate)
+	}
+	startDate, endDate, err := arrivalWindow(env, jobInfo)
+	if err != nil {
+		return nil, err
+	}
+	currTime := startDate
+	jobList, err := collectArrivals(env, jobInfo, endDate, func() time.Time {
+		currTime = currTime.Add(exponentialGap(env.Rand, pw.Rate))
+		return currTime
+	})
+	if err != nil {
+		return nil, err
+	}
+	env.Logger.Printf("Generated %d Poisson arrivals at %f jobs per hour\n", len(jobList), pw.Rate)
+	return jobList, nil
+}
+
+// GenerateWorkload generates arrivals by thinning a Poisson process running at
+// the peak rate of the profile.
+func (nw *NHPPWorkload) GenerateWorkload(env *environment.Environment, jobInfo JobMetadata) ([]*Job, error) {
+	if nw.Rate <= 0 {
+		return nil, fmt.Errorf("arrival rate must be positive, got %f", nw.Rate)
+	}
+	if len(nw.Profile) != 24 && len(nw.Profile) != 168 {
+		return nil, fmt.Errorf("rate profile needs 24 hourly or 168 weekly entries, got %d", len(nw.Profile))
+	}
+	peak := 0.0
+	for _, multiplier := range nw.Profile {
+		if multiplier < 0 {
+			return nil, fmt.Errorf("rate profile multipliers cannot be negative, got %f", multiplier)
+		}
+		peak = max(peak, multiplier)
+	}
+	if peak == 0 {
+		return nil, fmt.Errorf("rate profile is zero everywhere")
+	}
+	startDate, endDate, err := arrivalWindow(env, jobInfo)
+	if err != nil {
+		return nil, err
+	}
+	peakRate := nw.Rate * peak
+	currTime := startDate
+	jobList, err := collectArrivals(env, jobInfo, endDate, func() time.Time {
+		for {
+			currTime = currTime.Add(exponentialGap(env.Rand, peakRate))
+			if currTime.After(endDate) || env.Rand.Float64()*peak < nw.multiplier(currTime) {
+				return currTime
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	env.Logger.Printf("Generated %d arrivals from a %d hour rate profile peaking at %f jobs per hour\n", len(jobList), len(nw.Profile), peakRate)
+	return jobList, nil
+}
+
+// multiplier returns the profile entry covering t.
+func (nw *NHPPWorkload) multiplier(t time.Time) float64 {
+	if nw.Location != nil {
+		t = t.In(nw.Location)
+	}
+	if len(nw.Profile) == 24 {
+		return nw.Profile[t.Hour()]
+	}
+	// Weekly profiles start on Monday
+	day := (int(t.Weekday()) + 6) % 7
+	return nw.Profile[day*24+t.Hour()]
+}
+
+// GenerateWorkload generates arrivals while the process switches between
+// states after exponentially distributed dwell times.
+func (mw *MMPPWorkload) GenerateWorkload(env *environment.Environment, jobInfo JobMetadata) ([]*Job, error) {
+	if len(mw.States) < 2 {
+		return nil, fmt.Errorf("a Markov-modulated process needs at least two states, got %d", len(mw.States))
+	}
+	for _, state := range mw.States {
+		if state.Rate < 0 || state.MeanDwell <= 0 {
+			return nil, fmt.Errorf("invalid state with rate %f and mean dwell %v", state.Rate, state.MeanDwell)
+		}
+	}
+	startDate, endDate, err := arrivalWindow(env, jobInfo)
+	if err != nil {
+		return nil, err
+	}
+	currTime := startDate
+	currState := 0
+	switchTime := currTime.Add(time.Duration(env.Rand.ExpFloat64() * float64(mw.States[currState].MeanDwell)))
+	switches := 0
+	jobList, err := collectArrivals(env, jobInfo, endDate, func() time.Time {
+		for {
+			state := mw.States[currState]
+			if state.Rate > 0 {
+				candidate := currTime.Add(exponentialGap(env.Rand, state.Rate))
+				if candidate.Before(switchTime) {
+					currTime = candidate
+					return currTime
+				}
+			}
+			if switchTime.After(endDate) {
+				return switchTime
+			}
+			// Arrivals are memoryless, so the process restarts at the switch
+			currTime = switchTime
+			if next := env.Rand.Intn(len(mw.States) - 1); next >= currState {
+				currState = next + 1
+			} else {
+				currState = next
+			}
+			switchTime = currTime.Add(time.Duration(env.Rand.ExpFloat64() * float64(mw.States[currState].MeanDwell)))
+			switches++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	env.Logger.Printf("Generated %d Markov-modulated arrivals across %d state switches\n", len(jobList), switches)
+	return jobList, nil
+}
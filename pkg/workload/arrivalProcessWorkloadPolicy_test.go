@@ -0,0 +1,206 @@
+package workload
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// checkArrivals fails unless the jobs arrive in order within the window the
+// generators draw from and are due dueTime after arriving.
+func checkArrivals(t *testing.T, jobs []*Job, start, end time.Time, dueTime time.Duration) {
+	t.Helper()
+	for i, job := range jobs {
+		if job.StartTime.Before(start) || job.StartTime.After(end) {
+			t.Fatalf("job %d arrives at %v, outside %v to %v", i, job.StartTime, start, end)
+		}
+		if i > 0 && job.StartTime.Before(jobs[i-1].StartTime) {
+			t.Fatalf("job %d arrives at %v, before job %d at %v", i, job.StartTime, i-1, jobs[i-1].StartTime)
+		}
+		if !job.DueTime.Equal(job.StartTime.Add(dueTime)) {
+			t.Fatalf("job %d is due %v after arriving, want %v", i, job.DueTime.Sub(job.StartTime), dueTime)
+		}
+	}
+}
+
+// expectCount fails unless count lies within four standard deviations of a
+// Poisson count with the given mean.
+func expectCount(t *testing.T, count int, mean float64) {
+	t.Helper()
+	if math.Abs(float64(count)-mean) > 4*math.Sqrt(mean) {
+		t.Errorf("generated %d arrivals, want about %.0f", count, mean)
+	}
+}
+
+func TestPoissonWorkload(t *testing.T) {
+	env := testEnvironment(t, 1)
+	jobInfo := NewJobInfo(6*time.Hour, 0, "poisson")
+	jobs, err := NewPoissonWorkload(10).GenerateWorkload(env, jobInfo)
+	if err != nil {
+		t.Fatalf("GenerateWorkload: %v", err)
+	}
+	start, end := env.Loader.StartDate(), env.Loader.EndDate().Add(-jobInfo.DueTime)
+	checkArrivals(t, jobs, start, end, jobInfo.DueTime)
+	expectCount(t, len(jobs), 10*end.Sub(start).Hours())
+
+	again, err := NewPoissonWorkload(10).GenerateWorkload(testEnvironment(t, 1), jobInfo)
+	if err != nil {
+		t.Fatalf("GenerateWorkload: %v", err)
+	}
+	if len(again) != len(jobs) || !again[len(again)-1].StartTime.Equal(jobs[len(jobs)-1].StartTime) {
+		t.Errorf("the same seed generated different arrivals")
+	}
+
+	jobInfo.NumJobs = 50
+	limited, err := NewPoissonWorkload(10).GenerateWorkload(testEnvironment(t, 1), jobInfo)
+	if err != nil {
+		t.Fatalf("GenerateWorkload: %v", err)
+	}
+	if len(limited) != 50 || !limited[49].StartTime.Equal(jobs[49].StartTime) {
+		t.Errorf("job limit kept %d arrivals, want the first 50", len(limited))
+	}
+
+	if _, err := NewPoissonWorkload(0).GenerateWorkload(env, jobInfo); err == nil {
+		t.Errorf("zero arrival rate succeeded, want an error")
+	}
+}
+
+func TestNHPPWorkload(t *testing.T) {
+	office := make([]float64, 24)
+	for hour := 9; hour < 17; hour++ {
+		office[hour] = 1
+	}
+	saturday := make([]float64, 168)
+	for hour := 5 * 24; hour < 6*24; hour++ {
+		saturday[hour] = 2
+	}
+	tests := []struct {
+		name     string
+		profile  []float64
+		location *time.Location
+		inWindow func(t time.Time) bool
+		hours    float64 // Hours of a multiplier of 1 in the week of data
+	}{
+		{
+			name:     "hourly",
+			profile:  office,
+			inWindow: func(t time.Time) bool { return t.Hour() >= 9 && t.Hour() < 17 },
+			hours:    7 * 8,
+		},
+		{
+			name:     "hourly in local time",
+			profile:  office,
+			location: time.FixedZone("UTC+5", 5*60*60),
+			inWindow: func(t time.Time) bool {
+				local := t.In(time.FixedZone("UTC+5", 5*60*60))
+				return local.Hour() >= 9 && local.Hour() < 17
+			},
+			hours: 7 * 8,
+		},
+		{
+			name:     "weekly",
+			profile:  saturday,
+			inWindow: func(t time.Time) bool { return t.Weekday() == time.Saturday },
+			hours:    2 * 24,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := testEnvironment(t, 2)
+			jobInfo := NewJobInfo(time.Hour, 0, "nhpp")
+			jobs, err := NewNHPPWorkload(20, test.profile, test.location).GenerateWorkload(env, jobInfo)
+			if err != nil {
+				t.Fatalf("GenerateWorkload: %v", err)
+			}
+			checkArrivals(t, jobs, env.Loader.StartDate(), env.Loader.EndDate().Add(-jobInfo.DueTime), jobInfo.DueTime)
+			for i, job := range jobs {
+				if !test.inWindow(job.StartTime) {
+					t.Fatalf("job %d arrives at %v, where the profile is zero", i, job.StartTime)
+				}
+			}
+			expectCount(t, len(jobs), 20*test.hours)
+		})
+	}
+
+	errorTests := []struct {
+		name    string
+		profile []float64
+	}{
+		{name: "wrong length", profile: make([]float64, 12)},
+		{name: "negative", profile: append(slices.Clone(office[:23]), -1)},
+		{name: "zero everywhere", profile: make([]float64, 24)},
+	}
+	for _, test := range errorTests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewNHPPWorkload(20, test.profile, nil).GenerateWorkload(testEnvironment(t, 2), NewJobInfo(time.Hour, 0, "nhpp")); err == nil {
+				t.Errorf("GenerateWorkload succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestMMPPWorkload(t *testing.T) {
+	env := testEnvironment(t, 3)
+	jobInfo := NewJobInfo(time.Hour, 0, "mmpp")
+	states := []MMPPState{{Rate: 0, MeanDwell: 2 * time.Hour}, {Rate: 120, MeanDwell: 30 * time.Minute}}
+	jobs, err := NewMMPPWorkload(states).GenerateWorkload(env, jobInfo)
+	if err != nil {
+		t.Fatalf("GenerateWorkload: %v", err)
+	}
+	start, end := env.Loader.StartDate(), env.Loader.EndDate().Add(-jobInfo.DueTime)
+	checkArrivals(t, jobs, start, end, jobInfo.DueTime)
+	// Bursts at 120 an hour, half an hour in every two and a half
+	mean := 120 * end.Sub(start).Hours() / 5
+	if count := float64(len(jobs)); count < mean/2 || count > 2*mean {
+		t.Errorf("generated %d arrivals, want about %.0f", len(jobs), mean)
+	}
+	gaps := make([]time.Duration, len(jobs)-1)
+	for i := range gaps {
+		gaps[i] = jobs[i+1].StartTime.Sub(jobs[i].StartTime)
+	}
+	slices.Sort(gaps)
+	// Arrivals bunch into bursts separated by the silent state
+	if median, longest := gaps[len(gaps)/2], gaps[len(gaps)-1]; median > 5*time.Minute || longest < time.Hour {
+		t.Errorf("median gap %v and longest %v, want bursts of arrivals minutes apart separated by hours", median, longest)
+	}
+
+	errorStates := [][]MMPPState{
+		{{Rate: 10, MeanDwell: time.Hour}},
+		{{Rate: 10, MeanDwell: time.Hour}, {Rate: -1, MeanDwell: time.Hour}},
+		{{Rate: 10, MeanDwell: time.Hour}, {Rate: 1, MeanDwell: 0}},
+	}
+	for _, states := range errorStates {
+		if _, err := NewMMPPWorkload(states).GenerateWorkload(env, jobInfo); err == nil {
+			t.Errorf("states %v succeeded, want an error", states)
+		}
+	}
+}
+
+func TestParseMMPPStates(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []MMPPState
+		wantErr bool
+	}{
+		{spec: "20:2h,200:15m", want: []MMPPState{{20, 2 * time.Hour}, {200, 15 * time.Minute}}},
+		{spec: "0:1h,5:1h,50:10m", want: []MMPPState{{0, time.Hour}, {5, time.Hour}, {50, 10 * time.Minute}}},
+		{spec: "20:2h", wantErr: true},
+		{spec: "20,200:15m", wantErr: true},
+		{spec: "fast:2h,200:15m", wantErr: true},
+		{spec: "-1:2h,200:15m", wantErr: true},
+		{spec: "20:0s,200:15m", wantErr: true},
+		{spec: "20:soon,200:15m", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			states, err := ParseMMPPStates(test.spec)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseMMPPStates error = %v, want an error: %v", err, test.wantErr)
+			}
+			if !slices.Equal(states, test.want) {
+				t.Errorf("ParseMMPPStates = %v, want %v", states, test.want)
+			}
+		})
+	}
+}
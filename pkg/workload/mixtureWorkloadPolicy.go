@@ -0,0 +1,132 @@
+package workload
+
+import (
+	"fmt"
+	"math"
+	"simulator/pkg/environment"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MixtureComponent is one weighted generator of a mixture workload.
+type MixtureComponent struct {
+	Name   string          // Workload name of the component
+	Weight float64         // Relative share of the jobs
+	Policy PolicyInterface // Generator, nil until the workload is built
+}
+
+// MixtureWorkload combines several generators, giving each a share of the jobs
+// proportional to its weight.
+type MixtureWorkload struct {
+	Components []MixtureComponent
+}
+
+func NewMixtureWorkload(components []MixtureComponent) *MixtureWorkload {
+	return &MixtureWorkload{
+		Components: slices.Clone(components),
+	}
+}
+
+// ParseMixture parses comma separated name:weight pairs such as
+// "weekdaySpike:0.6,nightSpike:0.4".
+func ParseMixture(spec string) ([]MixtureComponent, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("the mixture workload requires components")
+	}
+	entries := strings.Split(spec, ",")
+	components := make([]MixtureComponent, len(entries))
+	for i, entry := range entries {
+		name, weight, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid mixture component %q, please use name:weight", entry)
+		}
+		parsedWeight, err := strconv.ParseFloat(weight, 64)
+		if err != nil || parsedWeight <= 0 {
+			return nil, fmt.Errorf("invalid weight %q for mixture component %s", weight, name)
+		}
+		if !slices.Contains(WorkloadPolicies, name) || name == "mixture" {
+			return nil, fmt.Errorf("invalid mixture component %q, please choose another workload", name)
+		}
+		for _, previous := range components[:i] {
+			if previous.Name == name {
+				return nil, fmt.Errorf("duplicate mixture component %s", name)
+			}
+		}
+		components[i] = MixtureComponent{Name: name, Weight: parsedWeight}
+	}
+	return components, nil
+}
+
+// GenerateWorkload splits jobInfo.NumJobs between the components by weight,
+// generates each share and merges the jobs in start time order. Every job
+// records the component that produced it. Rate driven components treat their
+// share as an upper bound, just as they do jobInfo.NumJobs.
+func (mw *MixtureWorkload) GenerateWorkload(env *environment.Environment, jobInfo JobMetadata) ([]*Job, error) {
+	if len(mw.Components) == 0 {
+		return nil, fmt.Errorf("the mixture workload requires components")
+	}
+	shares := mw.shares(jobInfo.NumJobs)
+	jobList := make([]*Job, 0, jobInfo.NumJobs)
+	for i, component := range mw.Components {
+		if component.Policy == nil {
+			return nil, fmt.Errorf("mixture component %s has no generator", component.Name)
+		}
+		if shares[i] == 0 {
+			env.Logger.Printf("Mixture component %s receives no jobs\n", component.Name)
+			continue
+		}
+		componentInfo := jobInfo
+		componentInfo.NumJobs = shares[i]
+		componentInfo.WorkloadPolicy = component.Name
+		jobs, err := component.Policy.GenerateWorkload(env, componentInfo)
+		if err != nil {
+			return nil, fmt.Errorf("mixture component %s: %w", component.Name, err)
+		}
+		for _, job := range jobs {
+			job.Component = component.Name
+		}
+		env.Logger.Printf("Mixture component %s generated %d jobs\n", component.Name, len(jobs))
+		jobList = append(jobList, jobs...)
+	}
+	// A stable sort keeps simultaneous arrivals in component order
+	slices.SortStableFunc(jobList, func(a, b *Job) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return jobList, nil
+}
+
+// shares divides numJobs by weight, handing leftover jobs to the components
+// with the largest remainders so the shares always sum to numJobs.
+func (mw *MixtureWorkload) shares(numJobs int) []int {
+	totalWeight := 0.0
+	for _, component := range mw.Components {
+		totalWeight += component.Weight
+	}
+	shares := make([]int, len(mw.Components))
+	remainders := make([]float64, len(mw.Components))
+	assigned := 0
+	for i, component := range mw.Components {
+		exact := float64(numJobs) * component.Weight / totalWeight
+		shares[i] = int(math.Floor(exact))
+		remainders[i] = exact - float64(shares[i])
+		assigned += shares[i]
+	}
+	order := make([]int, len(mw.Components))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		if remainders[a] > remainders[b] {
+			return -1
+		}
+		if remainders[a] < remainders[b] {
+			return 1
+		}
+		return 0
+	})
+	for _, index := range order[:min(max(numJobs-assigned, 0), len(order))] {
+		shares[index]++
+	}
+	return shares
+}
@@ -0,0 +1,102 @@
+package workload
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMixtureShares(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []float64
+		numJobs int
+		want    []int
+	}{
+		{name: "exact", weights: []float64{0.6, 0.4}, numJobs: 1000, want: []int{600, 400}},
+		{name: "largest remainder", weights: []float64{0.6, 0.4}, numJobs: 7, want: []int{4, 3}},
+		{name: "ties go to the first", weights: []float64{1, 1, 1}, numJobs: 10, want: []int{4, 3, 3}},
+		{name: "unnormalised weights", weights: []float64{1, 2, 5}, numJobs: 9, want: []int{1, 2, 6}},
+		{name: "tiny weight gets nothing", weights: []float64{1, 1000}, numJobs: 3, want: []int{0, 3}},
+		{name: "no jobs", weights: []float64{1, 2}, numJobs: 0, want: []int{0, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			components := make([]MixtureComponent, len(test.weights))
+			for i, weight := range test.weights {
+				components[i] = MixtureComponent{Weight: weight}
+			}
+			got := NewMixtureWorkload(components).shares(test.numJobs)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("shares = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMixtureWorkload(t *testing.T) {
+	components, err := ParseMixture("weekdaySpike:0.6,nightSpike:0.3,uniform:0.1")
+	if err != nil {
+		t.Fatalf("ParseMixture: %v", err)
+	}
+	jobInfo := NewJobInfo(time.Hour, 1001, "mixture")
+	jobInfo.Mixture = components
+	generated, err := GetWorkload(testEnvironment(t, 5), jobInfo)
+	if err != nil {
+		t.Fatalf("GetWorkload: %v", err)
+	}
+	env := testEnvironment(t, 5)
+	checkArrivals(t, generated.Jobs, env.Loader.StartDate(), env.Loader.EndDate(), time.Hour)
+	counts := make(map[string]int)
+	for _, job := range generated.Jobs {
+		counts[job.Component]++
+	}
+	want := map[string]int{"weekdaySpike": 601, "nightSpike": 300, "uniform": 100}
+	for name, count := range want {
+		if counts[name] != count {
+			t.Errorf("component %s generated %d jobs, want %d", name, counts[name], count)
+		}
+	}
+	if len(counts) != len(want) {
+		t.Errorf("jobs came from components %v, want %v", counts, want)
+	}
+
+	again, err := GetWorkload(testEnvironment(t, 5), jobInfo)
+	if err != nil {
+		t.Fatalf("GetWorkload: %v", err)
+	}
+	for i, job := range again.Jobs {
+		if !job.StartTime.Equal(generated.Jobs[i].StartTime) || job.Component != generated.Jobs[i].Component {
+			t.Fatalf("job %d differs between runs with the same seed", i)
+		}
+	}
+}
+
+func TestParseMixture(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []MixtureComponent
+		wantErr bool
+	}{
+		{spec: "weekdaySpike:0.6,nightSpike:0.4", want: []MixtureComponent{{Name: "weekdaySpike", Weight: 0.6}, {Name: "nightSpike", Weight: 0.4}}},
+		{spec: "poisson:2", want: []MixtureComponent{{Name: "poisson", Weight: 2}}},
+		{spec: "", wantErr: true},
+		{spec: "weekdaySpike", wantErr: true},
+		{spec: "weekdaySpike:0", wantErr: true},
+		{spec: "weekdaySpike:heavy", wantErr: true},
+		{spec: "bursty:1", wantErr: true},
+		{spec: "mixture:1", wantErr: true},
+		{spec: "uniform:1,uniform:2", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			components, err := ParseMixture(test.spec)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseMixture error = %v, want an error: %v", err, test.wantErr)
+			}
+			if !slices.Equal(components, test.want) {
+				t.Errorf("ParseMixture = %v, want %v", components, test.want)
+			}
+		})
+	}
+}
@@ -2,8 +2,7 @@ package workload
 
 import (
 	"fmt"
-	"math/rand"
-	"simulator/pkg/loader"
+	"simulator/pkg/environment"
 	"slices"
 	"time"
 )
@@ -11,8 +10,8 @@ import (
 type RandomWorkload struct{}
 
 // GenerateWorkload generates a random workload for the given model using the loader's data.
-func (rw *RandomWorkload) GenerateWorkload(jobInfo JobMetadata) ([]*Job, error) {
-	loader := loader.GetLoader()
+func (rw *RandomWorkload) GenerateWorkload(env *environment.Environment, jobInfo JobMetadata) ([]*Job, error) {
+	loader := env.Loader
 	if loader == nil {
 		return nil, fmt.Errorf("loader is not initialized")
 	}
@@ -22,16 +21,11 @@ func (rw *RandomWorkload) GenerateWorkload(jobInfo JobMetadata) ([]*Job, error)
 
 	// Generate jobs randomly within the loader's data range
 	for index := range jobInfo.NumJobs {
-		startTime, err := generateRandomTime(jobInfo.DueTime)
+		startTime, err := generateRandomTime(env, jobInfo.DueTime)
 		if err != nil {
 			return nil, err
 		}
-		job := &Job{
-			Model:     nil,
-			StartTime: startTime,
-			DueTime:   startTime.Add(jobInfo.DueTime),
-			EndTime:   startTime,
-		}
+		job := jobInfo.newJob(env.Rand, startTime)
 		jobList[index] = job
 	}
 	// Sort jobs by start time
@@ -42,8 +36,8 @@ func (rw *RandomWorkload) GenerateWorkload(jobInfo JobMetadata) ([]*Job, error)
 }
 
 // generateRandomTime generates a random time within the loader's data range.
-func generateRandomTime(SLOTime time.Duration) (time.Time, error) {
-	loader := loader.GetLoader()
+func generateRandomTime(env *environment.Environment, SLOTime time.Duration) (time.Time, error) {
+	loader := env.Loader
 	if loader == nil {
 		return time.Time{}, fmt.Errorf("loader is not initialized")
 	}
@@ -61,6 +55,6 @@ func generateRandomTime(SLOTime time.Duration) (time.Time, error) {
 	}
 
 	// Generate a random time between startDate and endDate
-	randomDuration := time.Duration(rand.Int63n(endDate.Sub(startDate).Nanoseconds()))
+	randomDuration := time.Duration(env.Rand.Int63n(endDate.Sub(startDate).Nanoseconds()))
 	return startDate.Add(randomDuration), nil
 }
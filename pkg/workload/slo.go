@@ -0,0 +1,156 @@
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sloDurations maps the named SLOs to their durations.
+var sloDurations = map[string]time.Duration{
+	"30min": 30 * time.Minute,
+	"1hr":   1 * time.Hour,
+	"6hr":   6 * time.Hour,
+	"12hr":  12 * time.Hour,
+	"24hr":  24 * time.Hour,
+}
+
+// SLOClass is a share of jobs that receive deadlines from the same range.
+type SLOClass struct {
+	Name       string        // Empty for an unnamed SLO
+	Weight     float64       // Relative share of jobs in the class
+	MinDueTime time.Duration // Shortest deadline in the class
+	MaxDueTime time.Duration // Longest deadline in the class, equal to MinDueTime for a fixed SLO
+}
+
+// SLOMix assigns each job a deadline drawn from one of its classes.
+type SLOMix struct {
+	Classes []SLOClass
+}
+
+// ParseSLO returns the duration of a named SLO or of any positive Go duration.
+func ParseSLO(name string) (time.Duration, error) {
+	if dueTime, ok := sloDurations[name]; ok {
+		return dueTime, nil
+	}
+	dueTime, err := time.ParseDuration(name)
+	if err != nil || dueTime <= 0 {
+		return 0, fmt.Errorf("invalid SLO %q, please choose 30min, 1hr, 6hr, 12hr, 24hr, or a positive duration such as 90m", name)
+	}
+	return dueTime, nil
+}
+
+// ParseSLOMix parses an SLO specification. It is either a single duration
+// ("6hr", "90m"), a uniform range ("30m-2h"), or comma separated classes of
+// the form name:weight:duration where each duration may itself be a range
+// ("interactive:0.3:30m,batch:0.7:24h").
+func ParseSLOMix(spec string) (*SLOMix, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("an SLO is required")
+	}
+	entries := strings.Split(spec, ",")
+	mix := &SLOMix{Classes: make([]SLOClass, 0, len(entries))}
+	names := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		class := SLOClass{Weight: 1}
+		dueTimes := entry
+		if parts := strings.Split(entry, ":"); len(parts) == 3 {
+			weight, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil || weight <= 0 {
+				return nil, fmt.Errorf("invalid weight %q for SLO class %s", parts[1], parts[0])
+			}
+			class.Name, class.Weight, dueTimes = parts[0], weight, parts[2]
+		} else if len(parts) != 1 {
+			return nil, fmt.Errorf("invalid SLO class %q, please use name:weight:duration", entry)
+		}
+		if len(entries) > 1 && class.Name == "" {
+			return nil, fmt.Errorf("SLO class %q needs a name and weight when several classes are given", entry)
+		}
+		if names[class.Name] {
+			return nil, fmt.Errorf("duplicate SLO class %s", class.Name)
+		}
+		names[class.Name] = true
+		minDue, maxDue, isRange := strings.Cut(dueTimes, "-")
+		var err error
+		if class.MinDueTime, err = ParseSLO(minDue); err != nil {
+			return nil, err
+		}
+		class.MaxDueTime = class.MinDueTime
+		if isRange {
+			if class.MaxDueTime, err = ParseSLO(maxDue); err != nil {
+				return nil, err
+			}
+			if class.MaxDueTime < class.MinDueTime {
+				return nil, fmt.Errorf("invalid SLO range %q, the upper bound is below the lower bound", dueTimes)
+			}
+		}
+		mix.Classes = append(mix.Classes, class)
+	}
+	return mix, nil
+}
+
+// FixedSLO returns a mix that gives every job the same deadline.
+func FixedSLO(dueTime time.Duration) *SLOMix {
+	return &SLOMix{Classes: []SLOClass{{Weight: 1, MinDueTime: dueTime, MaxDueTime: dueTime}}}
+}
+
+// MaxDueTime returns the longest deadline any job can receive.
+func (m *SLOMix) MaxDueTime() time.Duration {
+	longest := time.Duration(0)
+	for _, class := range m.Classes {
+		longest = max(longest, class.MaxDueTime)
+	}
+	return longest
+}
+
+// Draw returns a deadline and the class it came from. Fixed single-class mixes
+// consume no randomness, so their workloads match those of a plain SLO.
+func (m *SLOMix) Draw(r *rand.Rand) (time.Duration, string) {
+	class := m.Classes[0]
+	if len(m.Classes) > 1 {
+		totalWeight := 0.0
+		for _, candidate := range m.Classes {
+			totalWeight += candidate.Weight
+		}
+		target := r.Float64() * totalWeight
+		for _, candidate := range m.Classes {
+			class = candidate
+			target -= candidate.Weight
+			if target < 0 {
+				break
+			}
+		}
+	}
+	if class.MaxDueTime > class.MinDueTime {
+		return class.MinDueTime + time.Duration(r.Int63n(int64(class.MaxDueTime-class.MinDueTime))), class.Name
+	}
+	return class.MinDueTime, class.Name
+}
+
+// ClassNames returns the names of the mix's named classes in order.
+func (m *SLOMix) ClassNames() []string {
+	names := make([]string, 0, len(m.Classes))
+	for _, class := range m.Classes {
+		if class.Name != "" {
+			names = append(names, class.Name)
+		}
+	}
+	return names
+}
+
+func (m *SLOMix) String() string {
+	entries := make([]string, len(m.Classes))
+	for i, class := range m.Classes {
+		dueTimes := class.MinDueTime.String()
+		if class.MaxDueTime > class.MinDueTime {
+			dueTimes += "-" + class.MaxDueTime.String()
+		}
+		if class.Name != "" {
+			dueTimes = class.Name + ":" + strconv.FormatFloat(class.Weight, 'f', -1, 64) + ":" + dueTimes
+		}
+		entries[i] = dueTimes
+	}
+	return strings.Join(entries, ",")
+}
@@ -0,0 +1,122 @@
+package workload
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseSLO(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "30min", want: 30 * time.Minute},
+		{name: "1hr", want: time.Hour},
+		{name: "24hr", want: 24 * time.Hour},
+		{name: "90m", want: 90 * time.Minute},
+		{name: "1h30m", want: 90 * time.Minute},
+		{name: "0s", wantErr: true},
+		{name: "-1h", wantErr: true},
+		{name: "2hr", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseSLO(test.name)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseSLO error = %v, want an error: %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("ParseSLO = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSLOMix(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []SLOClass
+		wantErr bool
+	}{
+		{spec: "6hr", want: []SLOClass{{Weight: 1, MinDueTime: 6 * time.Hour, MaxDueTime: 6 * time.Hour}}},
+		{spec: "30m-2h", want: []SLOClass{{Weight: 1, MinDueTime: 30 * time.Minute, MaxDueTime: 2 * time.Hour}}},
+		{spec: "interactive:0.3:30min,batch:0.7:6hr-24hr", want: []SLOClass{
+			{Name: "interactive", Weight: 0.3, MinDueTime: 30 * time.Minute, MaxDueTime: 30 * time.Minute},
+			{Name: "batch", Weight: 0.7, MinDueTime: 6 * time.Hour, MaxDueTime: 24 * time.Hour},
+		}},
+		{spec: "only:2:1hr", want: []SLOClass{{Name: "only", Weight: 2, MinDueTime: time.Hour, MaxDueTime: time.Hour}}},
+		{spec: "", wantErr: true},
+		{spec: "6hr,12hr", wantErr: true},
+		{spec: "a:1:6hr,a:1:12hr", wantErr: true},
+		{spec: "a:0:6hr", wantErr: true},
+		{spec: "a:heavy:6hr", wantErr: true},
+		{spec: "a:6hr", wantErr: true},
+		{spec: "2h-30m", wantErr: true},
+		{spec: "soon", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			mix, err := ParseSLOMix(test.spec)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseSLOMix error = %v, want an error: %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if !slices.Equal(mix.Classes, test.want) {
+				t.Errorf("ParseSLOMix = %v, want %v", mix.Classes, test.want)
+			}
+			again, err := ParseSLOMix(mix.String())
+			if err != nil || !slices.Equal(again.Classes, mix.Classes) {
+				t.Errorf("parsing %q back gave %v, %v", mix.String(), again, err)
+			}
+		})
+	}
+}
+
+func TestSLOMixDraw(t *testing.T) {
+	mix, err := ParseSLOMix("interactive:1:30m-1h,batch:3:6hr")
+	if err != nil {
+		t.Fatalf("ParseSLOMix: %v", err)
+	}
+	if got := mix.MaxDueTime(); got != 6*time.Hour {
+		t.Errorf("MaxDueTime = %v, want 6h", got)
+	}
+	r := rand.New(rand.NewSource(9))
+	counts := make(map[string]int)
+	draws := 4000
+	for range draws {
+		dueTime, class := mix.Draw(r)
+		counts[class]++
+		switch class {
+		case "interactive":
+			if dueTime < 30*time.Minute || dueTime >= time.Hour {
+				t.Fatalf("interactive deadline %v outside 30m-1h", dueTime)
+			}
+		case "batch":
+			if dueTime != 6*time.Hour {
+				t.Fatalf("batch deadline %v, want 6h", dueTime)
+			}
+		default:
+			t.Fatalf("drew unknown class %q", class)
+		}
+	}
+	// A quarter of the draws, within four standard deviations
+	if interactive := float64(counts["interactive"]); math.Abs(interactive-1000) > 4*math.Sqrt(float64(draws)*0.25*0.75) {
+		t.Errorf("drew %v interactive deadlines out of %d, want about 1000", interactive, draws)
+	}
+
+	// Fixed SLOs draw nothing, leaving the stream for the rest of the workload
+	r, fresh := rand.New(rand.NewSource(9)), rand.New(rand.NewSource(9))
+	if dueTime, class := FixedSLO(time.Hour).Draw(r); dueTime != time.Hour || class != "" {
+		t.Errorf("fixed SLO drew %v in class %q, want 1h unnamed", dueTime, class)
+	}
+	if r.Int63() != fresh.Int63() {
+		t.Errorf("drawing a fixed SLO consumed randomness")
+	}
+}
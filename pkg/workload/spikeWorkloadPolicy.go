@@ -0,0 +1,251 @@
+package workload
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"simulator/pkg/environment"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpikeDays lists the day filters a spike can be restricted to.
+var SpikeDays = []string{"all", "weekday", "weekend"}
+
+// HourWindow covers the hours from Start up to but excluding End, both in
+// 0-24. Windows with End before Start wrap past midnight.
+type HourWindow struct {
+	Start int
+	End   int
+}
+
+type SpikeWorkload struct {
+	PercentOn  float64        // Percent of jobs that are during the spike
+	PercentOff float64        // Percent of jobs that are during the off-peak
+	Windows    []HourWindow   // Hours of the day the spike covers
+	Days       string         // Days the spike covers: all, weekday or weekend
+	Location   *time.Location // Zone the hours and days are in, UTC when nil
+}
+
+func NewSpikeWorkload(percentOn, percentOff float64, windows []HourWindow, days string) *SpikeWorkload {
+	return &SpikeWorkload{
+		PercentOn:  percentOn,
+		PercentOff: percentOff,
+		Windows:    windows,
+		Days:       days,
+	}
+}
+
+func NewMorningSpikeWorkload(percentOn, percentOff float64) *SpikeWorkload {
+	return NewSpikeWorkload(percentOn, percentOff, []HourWindow{{5, 12}}, "all")
+}
+
+func NewAfternoonSpikeWorkload(percentOn, percentOff float64) *SpikeWorkload {
+	return NewSpikeWorkload(percentOn, percentOff, []HourWindow{{12, 17}}, "all")
+}
+
+func NewEveningSpikeWorkload(percentOn, percentOff float64) *SpikeWorkload {
+	return NewSpikeWorkload(percentOn, percentOff, []HourWindow{{17, 24}}, "all")
+}
+
+func NewNightSpikeWorkload(percentOn, percentOff float64) *SpikeWorkload {
+	return NewSpikeWorkload(percentOn, percentOff, []HourWindow{{0, 5}}, "all")
+}
+
+func NewWeekdaySpikeWorkload(percentOn, percentOff float64) *SpikeWorkload {
+	return NewSpikeWorkload(percentOn, percentOff, []HourWindow{{0, 24}}, "weekday")
+}
+
+func NewWeekendSpikeWorkload(percentOn, percentOff float64) *SpikeWorkload {
+	return NewSpikeWorkload(percentOn, percentOff, []HourWindow{{0, 24}}, "weekend")
+}
+
+// ParseHourWindows parses comma separated hour ranges such as "5-12,17-20".
+// A range may wrap past midnight, as in "22-4".
+func ParseHourWindows(spec string) ([]HourWindow, error) {
+	entries := strings.Split(spec, ",")
+	windows := make([]HourWindow, len(entries))
+	for i, entry := range entries {
+		start, end, found := strings.Cut(strings.TrimSpace(entry), "-")
+		if !found {
+			return nil, fmt.Errorf("invalid hour window %q, please use start-end such as 5-12", entry)
+		}
+		startHour, err := strconv.Atoi(start)
+		if err != nil || startHour < 0 || startHour > 23 {
+			return nil, fmt.Errorf("invalid start hour %q in window %q", start, entry)
+		}
+		endHour, err := strconv.Atoi(end)
+		if err != nil || endHour < 0 || endHour > 24 {
+			return nil, fmt.Errorf("invalid end hour %q in window %q", end, entry)
+		}
+		if startHour == endHour {
+			return nil, fmt.Errorf("hour window %q is empty", entry)
+		}
+		windows[i] = HourWindow{Start: startHour, End: endHour}
+	}
+	return windows, nil
+}
+
+func (h HourWindow) contains(hour int) bool {
+	if h.Start < h.End {
+		return hour >= h.Start && hour < h.End
+	}
+	return hour >= h.Start || hour < h.End
+}
+
+func (h HourWindow) String() string {
+	return fmt.Sprintf("%d-%d", h.Start, h.End)
+}
+
+// inSpike reports whether the hour starting at t belongs to the spike.
+func (sp *SpikeWorkload) inSpike(t time.Time) bool {
+	t = t.In(sp.location())
+	switch sp.Days {
+	case "weekday":
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+			return false
+		}
+	case "weekend":
+		if t.Weekday() != time.Saturday && t.Weekday() != time.Sunday {
+			return false
+		}
+	}
+	for _, window := range sp.Windows {
+		if window.contains(t.Hour()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (sp *SpikeWorkload) String() string {
+	windows := make([]string, len(sp.Windows))
+	for i, window := range sp.Windows {
+		windows[i] = window.String()
+	}
+	days := "every day"
+	switch sp.Days {
+	case "weekday":
+		days = "on weekdays"
+	case "weekend":
+		days = "on weekends"
+	}
+	return fmt.Sprintf("hours %s %s in %s", strings.Join(windows, ","), days, sp.location())
+}
+
+func (sp *SpikeWorkload) location() *time.Location {
+	if sp.Location == nil {
+		return time.UTC
+	}
+	return sp.Location
+}
+
+// hourSlots collects the parts of each hour between startDate and endDate that
+// fall inside (or outside) the spike, with their cumulative lengths so a time
+// can be drawn uniformly across them.
+type hourSlots struct {
+	starts     []time.Time
+	cumulative []time.Duration
+}
+
+func (sp *SpikeWorkload) hourSlots(startDate, endDate time.Time, spike bool) hourSlots {
+	slots := hourSlots{}
+	total := time.Duration(0)
+	// Hours are stepped in absolute time from a local hour boundary, which
+	// keeps them aligned in zones offset by a fraction of an hour
+	local := startDate.In(sp.location())
+	firstHour := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, local.Location()).UTC()
+	for hour := firstHour; hour.Before(endDate); hour = hour.Add(time.Hour) {
+		if sp.inSpike(hour) != spike {
+			continue
+		}
+		slotStart := hour
+		if slotStart.Before(startDate) {
+			slotStart = startDate
+		}
+		slotEnd := hour.Add(time.Hour)
+		if slotEnd.After(endDate) {
+			slotEnd = endDate
+		}
+		total += slotEnd.Sub(slotStart)
+		slots.starts = append(slots.starts, slotStart)
+		slots.cumulative = append(slots.cumulative, total)
+	}
+	return slots
+}
+
+func (h hourSlots) total() time.Duration {
+	if len(h.cumulative) == 0 {
+		return 0
+	}
+	return h.cumulative[len(h.cumulative)-1]
+}
+
+// draw returns a time uniformly distributed across the slots.
+func (h hourSlots) draw(r *rand.Rand) time.Time {
+	offset := time.Duration(r.Int63n(int64(h.total())))
+	index := sort.Search(len(h.cumulative), func(i int) bool {
+		return h.cumulative[i] > offset
+	})
+	slotOffset := offset
+	if index > 0 {
+		slotOffset -= h.cumulative[index-1]
+	}
+	return h.starts[index].Add(slotOffset)
+}
+
+// GenerateWorkload places PercentOn of the jobs uniformly within the spike and
+// the rest uniformly outside it.
+func (sp *SpikeWorkload) GenerateWorkload(env *environment.Environment, jobInfo JobMetadata) ([]*Job, error) {
+	loader := env.Loader
+	if loader == nil {
+		return nil, fmt.Errorf("loader is not initialized")
+	}
+
+	if loader.NumEntries() == 0 {
+		return nil, fmt.Errorf("no data available in loader")
+	}
+	if len(sp.Windows) == 0 {
+		return nil, fmt.Errorf("spike workload has no hour windows")
+	}
+	if sp.Days != "" && !slices.Contains(SpikeDays, sp.Days) {
+		return nil, fmt.Errorf("invalid spike days %q, please choose %s", sp.Days, strings.Join(SpikeDays, ", "))
+	}
+
+	numJobs := jobInfo.NumJobs
+	numOnSpike := int(math.Round(float64(numJobs) * sp.PercentOn))
+	if numOnSpike > numJobs {
+		numOnSpike = numJobs
+	}
+	numOffSpike := numJobs - numOnSpike
+
+	startDate := loader.StartDate()
+	endDate := loader.EndDate().Add(-jobInfo.DueTime)
+	if !endDate.After(startDate) {
+		return nil, fmt.Errorf("invalid loader date range")
+	}
+	onSlots := sp.hourSlots(startDate, endDate, true)
+	offSlots := sp.hourSlots(startDate, endDate, false)
+	if numOnSpike > 0 && onSlots.total() == 0 {
+		return nil, fmt.Errorf("the spike (%s) does not occur within the data range", sp)
+	}
+	if numOffSpike > 0 && offSlots.total() == 0 {
+		return nil, fmt.Errorf("the spike (%s) leaves no off-peak time within the data range", sp)
+	}
+
+	jobList := make([]*Job, numJobs)
+	env.Logger.Printf("Putting %d jobs in the spike period (%s) and %d jobs in the off-peak period\n", numOnSpike, sp, numOffSpike)
+	for i := range numOnSpike {
+		jobList[i] = jobInfo.newJob(env.Rand, onSlots.draw(env.Rand))
+	}
+	for i := range numOffSpike {
+		jobList[numOnSpike+i] = jobInfo.newJob(env.Rand, offSlots.draw(env.Rand))
+	}
+	slices.SortFunc(jobList, func(a, b *Job) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return jobList, nil
+}
@@ -0,0 +1,134 @@
+package workload
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseHourWindows(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []HourWindow
+		wantErr bool
+	}{
+		{spec: "5-12", want: []HourWindow{{5, 12}}},
+		{spec: "5-12, 17-24", want: []HourWindow{{5, 12}, {17, 24}}},
+		{spec: "22-4", want: []HourWindow{{22, 4}}},
+		{spec: "0-24", want: []HourWindow{{0, 24}}},
+		{spec: "5", wantErr: true},
+		{spec: "5-5", wantErr: true},
+		{spec: "24-3", wantErr: true},
+		{spec: "3-25", wantErr: true},
+		{spec: "morning-noon", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			windows, err := ParseHourWindows(test.spec)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseHourWindows error = %v, want an error: %v", err, test.wantErr)
+			}
+			if !slices.Equal(windows, test.want) {
+				t.Errorf("ParseHourWindows = %v, want %v", windows, test.want)
+			}
+		})
+	}
+}
+
+func TestSpikeWorkload(t *testing.T) {
+	india := time.FixedZone("UTC+5:30", 5*60*60+30*60)
+	pacific := time.FixedZone("UTC-8", -8*60*60)
+	tests := []struct {
+		name     string
+		workload string
+		windows  []HourWindow
+		days     string
+		location *time.Location
+		inSpike  func(t time.Time) bool
+	}{
+		{
+			name:     "morning",
+			workload: "morningSpike",
+			inSpike:  func(t time.Time) bool { return t.Hour() >= 5 && t.Hour() < 12 },
+		},
+		{
+			name:     "night in local time",
+			workload: "nightSpike",
+			location: india,
+			inSpike:  func(t time.Time) bool { return t.In(india).Hour() < 5 },
+		},
+		{
+			name:     "weekend in local time",
+			workload: "weekendSpike",
+			location: pacific,
+			inSpike: func(t time.Time) bool {
+				day := t.In(pacific).Weekday()
+				return day == time.Saturday || day == time.Sunday
+			},
+		},
+		{
+			name:     "windows wrapping past midnight",
+			workload: "spike",
+			windows:  []HourWindow{{22, 4}, {12, 13}},
+			inSpike:  func(t time.Time) bool { return t.Hour() >= 22 || t.Hour() < 4 || t.Hour() == 12 },
+		},
+		{
+			name:     "configured windows and days override a named spike",
+			workload: "morningSpike",
+			windows:  []HourWindow{{17, 20}},
+			days:     "weekday",
+			inSpike: func(t time.Time) bool {
+				return t.Hour() >= 17 && t.Hour() < 20 && t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := testEnvironment(t, 6)
+			jobInfo := NewJobInfo(2*time.Hour, 1000, test.workload)
+			jobInfo.SpikeFraction = 0.7
+			jobInfo.SpikeWindows = test.windows
+			jobInfo.SpikeDays = test.days
+			jobInfo.Location = test.location
+			generated, err := GetWorkload(env, jobInfo)
+			if err != nil {
+				t.Fatalf("GetWorkload: %v", err)
+			}
+			if len(generated.Jobs) != 1000 {
+				t.Fatalf("generated %d jobs, want 1000", len(generated.Jobs))
+			}
+			checkArrivals(t, generated.Jobs, env.Loader.StartDate(), env.Loader.EndDate().Add(-jobInfo.DueTime), jobInfo.DueTime)
+			inSpike := 0
+			for _, job := range generated.Jobs {
+				if test.inSpike(job.StartTime) {
+					inSpike++
+				}
+			}
+			if inSpike != 700 {
+				t.Errorf("%d jobs arrive in the spike, want 700", inSpike)
+			}
+		})
+	}
+}
+
+func TestSpikeWorkloadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		workload *SpikeWorkload
+	}{
+		{name: "no windows", workload: NewSpikeWorkload(0.8, 0.2, nil, "all")},
+		{name: "invalid days", workload: NewSpikeWorkload(0.8, 0.2, []HourWindow{{5, 12}}, "holiday")},
+		{name: "no off-peak time", workload: NewSpikeWorkload(0.8, 0.2, []HourWindow{{0, 24}}, "all")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.workload.GenerateWorkload(testEnvironment(t, 6), NewJobInfo(time.Hour, 100, "spike")); err == nil {
+				t.Errorf("GenerateWorkload succeeded, want an error")
+			}
+		})
+	}
+	// A spike covering everything is fine when every job falls in it
+	if _, err := NewSpikeWorkload(1, 0, []HourWindow{{0, 24}}, "all").GenerateWorkload(testEnvironment(t, 6), NewJobInfo(time.Hour, 100, "spike")); err != nil {
+		t.Errorf("GenerateWorkload with every job in an all day spike: %v", err)
+	}
+}
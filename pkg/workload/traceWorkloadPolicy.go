@@ -0,0 +1,321 @@
+package workload
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"simulator/pkg/environment"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceFormats lists the arrival log formats TraceWorkload reads.
+var TraceFormats = []string{"csv", "jsonl"}
+
+// TraceAlignments lists the ways TraceWorkload can shift arrivals onto the
+// carbon data. "none" keeps the logged times, "start" moves the first arrival
+// to the start of the data, and "week" shifts by whole weeks so the first
+// arrival lands in the first week of the data while keeping the time of day
+// and day of week of every arrival.
+var TraceAlignments = []string{"none", "start", "week"}
+
+// TraceWorkload replays arrivals recorded in a CSV or JSON Lines file.
+type TraceWorkload struct {
+	Path   string // Arrival log to replay
+	Format string // csv or jsonl, inferred from the extension when empty
+	Align  string // One of TraceAlignments, none when empty
+}
+
+// traceArrival is one logged request. Deadline is either an absolute time or
+// a duration after the arrival.
+type traceArrival struct {
+	Timestamp string
+	Deadline  string
+	Class     string
+	Accuracy  *float64
+	Tenant    string
+}
+
+func NewTraceWorkload(path, format, align string) *TraceWorkload {
+	return &TraceWorkload{
+		Path:   path,
+		Format: format,
+		Align:  align,
+	}
+}
+
+// GenerateWorkload reads the arrival log. Arrivals without a deadline draw
+// one from the workload's SLOs, those with one keep the class logged with it,
+// and at most jobInfo.NumJobs arrivals are kept.
+// Arrivals whose deadline falls outside the carbon data are dropped.
+func (tw *TraceWorkload) GenerateWorkload(env *environment.Environment, jobInfo JobMetadata) ([]*Job, error) {
+	loader := env.Loader
+	if loader == nil {
+		return nil, fmt.Errorf("loader is not initialized")
+	}
+	if loader.NumEntries() == 0 {
+		return nil, fmt.Errorf("no data available in loader")
+	}
+
+	arrivals, err := tw.readArrivals()
+	if err != nil {
+		return nil, err
+	}
+	if len(arrivals) == 0 {
+		return nil, fmt.Errorf("arrival log %s has no arrivals", tw.Path)
+	}
+
+	jobList := make([]*Job, 0, len(arrivals))
+	for index, arrival := range arrivals {
+		startTime, err := parseTraceTime(arrival.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("arrival %d: invalid timestamp: %w", index+1, err)
+		}
+		job := jobInfo.newJob(env.Rand, startTime)
+		if arrival.Deadline != "" {
+			if job.DueTime, err = parseTraceDeadline(startTime, arrival.Deadline); err != nil {
+				return nil, fmt.Errorf("arrival %d: invalid deadline: %w", index+1, err)
+			}
+			job.SLOClass = arrival.Class
+		}
+		if arrival.Accuracy != nil {
+			if *arrival.Accuracy < 0 || *arrival.Accuracy > 1 {
+				return nil, fmt.Errorf("arrival %d: required accuracy must be between 0 and 1, got %f", index+1, *arrival.Accuracy)
+			}
+			job.RequiredAccuracy = *arrival.Accuracy
+		}
+		job.Tenant = arrival.Tenant
+		jobList = append(jobList, job)
+	}
+	slices.SortStableFunc(jobList, func(a, b *Job) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+
+	shift, err := tw.alignment(jobList[0].StartTime, loader.StartDate())
+	if err != nil {
+		return nil, err
+	}
+	kept := jobList[:0]
+	for _, job := range jobList {
+		job.StartTime = job.StartTime.Add(shift)
+		job.EndTime = job.StartTime
+		job.DueTime = job.DueTime.Add(shift)
+		if job.StartTime.Before(loader.StartDate()) || job.DueTime.After(loader.EndDate()) {
+			continue
+		}
+		if jobInfo.NumJobs > 0 && len(kept) == jobInfo.NumJobs {
+			break
+		}
+		kept = append(kept, job)
+	}
+	if dropped := len(arrivals) - len(kept); dropped > 0 {
+		env.Logger.Printf("Replaying %d of %d arrivals from %s, the rest fall outside the carbon data or the job limit\n", len(kept), len(arrivals), tw.Path)
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no arrivals in %s fall within the carbon data from %v to %v", tw.Path, loader.StartDate().Format(time.RFC3339), loader.EndDate().Format(time.RFC3339))
+	}
+	if shift != 0 {
+		env.Logger.Printf("Shifted arrivals by %v to align with the carbon data\n", shift)
+	}
+	return kept, nil
+}
+
+// alignment returns how far arrivals are shifted so the first one lines up
+// with the start of the carbon data.
+func (tw *TraceWorkload) alignment(firstArrival, dataStart time.Time) (time.Duration, error) {
+	switch tw.Align {
+	case "", "none":
+		return 0, nil
+	case "start":
+		return dataStart.Sub(firstArrival), nil
+	case "week":
+		week := 7 * 24 * time.Hour
+		weeks := math.Ceil(float64(dataStart.Sub(firstArrival)) / float64(week))
+		return time.Duration(weeks) * week, nil
+	default:
+		return 0, fmt.Errorf("invalid alignment %q, please choose %s", tw.Align, strings.Join(TraceAlignments, ", "))
+	}
+}
+
+func (tw *TraceWorkload) readArrivals() ([]traceArrival, error) {
+	file, err := os.Open(tw.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening arrival log: %w", err)
+	}
+	defer file.Close()
+	format := tw.Format
+	if format == "" {
+		format = InferTraceFormat(tw.Path)
+	}
+	switch format {
+	case "csv":
+		return readCSVArrivals(file)
+	case "jsonl":
+		return readJSONLArrivals(file)
+	default:
+		return nil, fmt.Errorf("invalid arrival log format %q, please choose %s", format, strings.Join(TraceFormats, ", "))
+	}
+}
+
+// InferTraceFormat guesses the arrival log format from the file extension.
+func InferTraceFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson", ".json":
+		return "jsonl"
+	default:
+		return "csv"
+	}
+}
+
+// readCSVArrivals reads a CSV file with a header row. Only the timestamp
+// column is required; deadline, class, accuracy and tenant are optional.
+func readCSVArrivals(r io.Reader) ([]traceArrival, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading arrival log header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["timestamp"]; !ok {
+		return nil, fmt.Errorf("arrival log has no timestamp column")
+	}
+	if _, ok := columns["accuracy"]; !ok {
+		if index, ok := columns["required_accuracy"]; ok {
+			columns["accuracy"] = index
+		}
+	}
+	field := func(record []string, name string) string {
+		index, ok := columns[name]
+		if !ok || index >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[index])
+	}
+
+	arrivals := make([]traceArrival, 0)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading arrival log: %w", err)
+		}
+		arrival := traceArrival{
+			Timestamp: field(record, "timestamp"),
+			Deadline:  field(record, "deadline"),
+			Class:     field(record, "class"),
+			Tenant:    field(record, "tenant"),
+		}
+		if value := field(record, "accuracy"); value != "" {
+			accuracy, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid accuracy %q", line, value)
+			}
+			arrival.Accuracy = &accuracy
+		}
+		arrivals = append(arrivals, arrival)
+	}
+	return arrivals, nil
+}
+
+// readJSONLArrivals reads one JSON object per line. Timestamps may be given
+// as strings or as Unix seconds.
+func readJSONLArrivals(r io.Reader) ([]traceArrival, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	arrivals := make([]traceArrival, 0)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var raw struct {
+			Timestamp        json.RawMessage `json:"timestamp"`
+			Deadline         json.RawMessage `json:"deadline"`
+			Class            string          `json:"class"`
+			Accuracy         *float64        `json:"accuracy"`
+			RequiredAccuracy *float64        `json:"required_accuracy"`
+			Tenant           string          `json:"tenant"`
+		}
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(raw.Timestamp) == 0 {
+			return nil, fmt.Errorf("line %d: missing timestamp", line)
+		}
+		arrival := traceArrival{
+			Timestamp: rawJSONString(raw.Timestamp),
+			Deadline:  rawJSONString(raw.Deadline),
+			Class:     raw.Class,
+			Accuracy:  raw.Accuracy,
+			Tenant:    raw.Tenant,
+		}
+		if arrival.Accuracy == nil {
+			arrival.Accuracy = raw.RequiredAccuracy
+		}
+		arrivals = append(arrivals, arrival)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading arrival log: %w", err)
+	}
+	return arrivals, nil
+}
+
+// rawJSONString returns a JSON string's contents, or the literal text of any
+// other value such as a number.
+func rawJSONString(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+// parseTraceTime accepts RFC 3339 timestamps, "2006-01-02 15:04:05" in UTC,
+// and Unix seconds.
+func parseTraceTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return parsed.UTC(), nil
+	}
+	if parsed, err := time.Parse(time.DateTime, value); err == nil {
+		return parsed, nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		whole, fraction := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(fraction*1e9)).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or Unix seconds", value)
+}
+
+// parseTraceDeadline accepts an absolute time or a duration after the arrival.
+func parseTraceDeadline(arrival time.Time, value string) (time.Time, error) {
+	if relative, err := time.ParseDuration(value); err == nil {
+		if relative <= 0 {
+			return time.Time{}, fmt.Errorf("relative deadline %q must be positive", value)
+		}
+		return arrival.Add(relative), nil
+	}
+	deadline, err := parseTraceTime(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if deadline.Before(arrival) {
+		return time.Time{}, fmt.Errorf("deadline %v is before the arrival at %v", deadline.Format(time.RFC3339), arrival.Format(time.RFC3339))
+	}
+	return deadline, nil
+}
@@ -2,15 +2,15 @@ package workload
 
 import (
 	"fmt"
-	"simulator/pkg/loader"
+	"simulator/pkg/environment"
 	"time"
 )
 
 type UniformWorkload struct{}
 
 // GenerateWorkload generates a uniform workload for the given model using the loader's data.
-func (uw *UniformWorkload) GenerateWorkload(jobInfo JobMetadata) ([]*Job, error) {
-	loader := loader.GetLoader()
+func (uw *UniformWorkload) GenerateWorkload(env *environment.Environment, jobInfo JobMetadata) ([]*Job, error) {
+	loader := env.Loader
 	if loader == nil {
 		return nil, fmt.Errorf("loader is not initialized")
 	}
@@ -36,12 +36,7 @@ func (uw *UniformWorkload) GenerateWorkload(jobInfo JobMetadata) ([]*Job, error)
 	for index := range jobInfo.NumJobs {
 		startTime := startDate.Add(time.Duration(index) * interval)
 
-		job := &Job{
-			Model:     nil,
-			StartTime: startTime,
-			DueTime:   startTime.Add(jobInfo.DueTime),
-			EndTime:   startTime,
-		}
+		job := jobInfo.newJob(env.Rand, startTime)
 		jobList[index] = job
 	}
 
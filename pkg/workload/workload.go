@@ -1,131 +1,156 @@
 package workload
 
 import (
-	"math"
+	"fmt"
 	"math/rand"
-	"simulator/pkg/loader"
+	"simulator/pkg/environment"
 	"time"
 )
 
+// DefaultSpikeFraction is the share of jobs spike workloads place in their spike.
+const DefaultSpikeFraction = 0.8
+
+// WorkloadPolicies lists the workload names GetWorkload accepts.
+var WorkloadPolicies = []string{
+	"random", "uniform", "spike", "morningSpike", "afternoonSpike", "eveningSpike", "nightSpike", "weekdaySpike", "weekendSpike", "trace", "poisson", "nhpp", "mmpp", "mixture",
+}
+
 func NewJobInfo(dueTime time.Duration, numJobs int, workloadPolicy string) JobMetadata {
 	return JobMetadata{
 		DueTime:        dueTime,
+		SLOs:           FixedSLO(dueTime),
 		NumJobs:        numJobs,
 		WorkloadPolicy: workloadPolicy,
+		SpikeFraction:  DefaultSpikeFraction,
+	}
+}
+
+func GetWorkload(env *environment.Environment, jobInfo JobMetadata) (Workload, error) {
+	if env == nil || env.Loader == nil {
+		return Workload{}, fmt.Errorf("loader is not initialized")
+	}
+	spikeFraction := jobInfo.SpikeFraction
+	if spikeFraction < 0 || spikeFraction > 1 {
+		return Workload{}, fmt.Errorf("spike fraction must be between 0 and 1, got %f", spikeFraction)
+	}
+	if jobInfo.SLOs == nil {
+		jobInfo.SLOs = FixedSLO(jobInfo.DueTime)
+	}
+	if jobInfo.Location == nil {
+		jobInfo.Location = env.Loader.Location()
+	}
+	// Generators keep arrivals far enough from the end of the data for the longest deadline
+	jobInfo.DueTime = jobInfo.SLOs.MaxDueTime()
+	workload, err := newWorkloadPolicy(jobInfo.WorkloadPolicy, jobInfo, spikeFraction)
+	if err != nil {
+		return Workload{}, err
+	}
+
+	jobs, err := workload.GenerateWorkload(env, jobInfo)
+	if err != nil {
+		return Workload{}, fmt.Errorf("failed to generate workload: %w", err)
 	}
+	return Workload{
+		Policy: workload,
+		Jobs:   jobs,
+	}, nil
 }
 
-func GetWorkload(jobInfo JobMetadata) Workload {
-	loader := loader.GetLoader()
-	if loader == nil {
-		panic("Loader is not initialized")
+// newJob returns a job arriving at startTime with a deadline drawn from the
+// workload's SLOs.
+func (j JobMetadata) newJob(r *rand.Rand, startTime time.Time) *Job {
+	dueTime, class := j.SLOs.Draw(r)
+	return &Job{
+		Model:     nil,
+		StartTime: startTime,
+		DueTime:   startTime.Add(dueTime),
+		EndTime:   startTime,
+		SLOClass:  class,
 	}
+}
+
+// newWorkloadPolicy returns the generator registered under name.
+func newWorkloadPolicy(name string, jobInfo JobMetadata, spikeFraction float64) (PolicyInterface, error) {
 	var workload PolicyInterface
-	switch jobInfo.WorkloadPolicy {
+	switch name {
 	case "random":
 		workload = &RandomWorkload{}
 	case "uniform":
 		workload = &UniformWorkload{}
+	case "spike":
+		// Spike defined entirely by the configured windows and days
+		workload = NewSpikeWorkload(spikeFraction, 1-spikeFraction, []HourWindow{{0, 24}}, "all")
 	case "morningSpike":
 		// Morning spike from 5 AM to 12 PM
-		workload = NewMorningSpikeWorkload(0.8, 0.2)
+		workload = NewMorningSpikeWorkload(spikeFraction, 1-spikeFraction)
 	case "afternoonSpike":
 		// Afternoon spike from 12 PM to 5 PM
-		workload = NewAfternoonSpikeWorkload(0.8, 0.2)
+		workload = NewAfternoonSpikeWorkload(spikeFraction, 1-spikeFraction)
 	case "eveningSpike":
 		// Evening spike from 5 PM to 12 AM
-		workload = NewEveningSpikeWorkload(0.8, 0.2)
+		workload = NewEveningSpikeWorkload(spikeFraction, 1-spikeFraction)
 	case "nightSpike":
 		// Night spike from 12 AM to 5 AM
-		workload = NewNightSpikeWorkload(0.8, 0.2)
+		workload = NewNightSpikeWorkload(spikeFraction, 1-spikeFraction)
 	case "weekdaySpike":
 		// Assigns jobs to weekdays
-		workload = NewWeekdaySpikeWorkload(0.8, 0.2)
+		workload = NewWeekdaySpikeWorkload(spikeFraction, 1-spikeFraction)
 	case "weekendSpike":
 		// Assigns jobs to weekends
-		workload = NewWeekendSpikeWorkload(0.8, 0.2)
+		workload = NewWeekendSpikeWorkload(spikeFraction, 1-spikeFraction)
+	case "trace":
+		// Replays logged arrivals
+		if jobInfo.TracePath == "" {
+			return nil, fmt.Errorf("the trace workload requires an arrival log")
+		}
+		workload = NewTraceWorkload(jobInfo.TracePath, jobInfo.TraceFormat, jobInfo.TraceAlign)
+	case "poisson":
+		// Exponential gaps at a constant rate
+		workload = NewPoissonWorkload(jobInfo.ArrivalRate)
+	case "nhpp":
+		// Rate varies with the hour of the day or week
+		workload = NewNHPPWorkload(jobInfo.ArrivalRate, jobInfo.RateProfile, jobInfo.Location)
+	case "mmpp":
+		// Bursty arrivals switching between rates
+		workload = NewMMPPWorkload(jobInfo.MMPPStates)
+	case "mixture":
+		// Combines the other generators by weight
+		if len(jobInfo.Mixture) == 0 {
+			return nil, fmt.Errorf("the mixture workload requires components")
+		}
+		mixture := NewMixtureWorkload(jobInfo.Mixture)
+		for i, component := range mixture.Components {
+			if component.Name == "mixture" {
+				return nil, fmt.Errorf("mixtures cannot contain other mixtures")
+			}
+			policy, err := newWorkloadPolicy(component.Name, jobInfo, spikeFraction)
+			if err != nil {
+				return nil, fmt.Errorf("mixture component %s: %w", component.Name, err)
+			}
+			mixture.Components[i].Policy = policy
+		}
+		workload = mixture
 	default:
-		panic("Unknown workload policy: " + jobInfo.WorkloadPolicy)
-	}
-
-	jobs, err := workload.GenerateWorkload(jobInfo)
-	if err != nil {
-		panic("Failed to generate workload: " + err.Error())
+		return nil, fmt.Errorf("unknown workload policy: %s", name)
 	}
-	return Workload{
-		Policy: workload,
-		Jobs:   jobs,
-	}
-}
-
-func getStartTimeInRange(startDate, endDate time.Time, startHour, endHour int) time.Time {
-	// Generate a random time within the specified hour range
-	duration := endDate.Sub(startDate)
-	totalDays := int(duration.Hours() / 24)
-	if endHour < startHour {
-		endHour += 24
-	}
-
-	randomDay := rand.Intn(totalDays + 1)
-	chosenDate := startDate.AddDate(0, 0, randomDay)
-
-	randomHour := (rand.Intn(endHour-startHour) + startHour) % 24
-	randomMinute := rand.Intn(60)
-	randomSecond := rand.Intn(60)
-
-	retVal := time.Date(chosenDate.Year(), chosenDate.Month(), chosenDate.Day(),
-		randomHour, randomMinute, randomSecond, 0, chosenDate.Location())
-
-	// Ensure the generated time is within the specified range
-	if retVal.Before(startDate) {
-		// If the generated time is before the start date, add days to make it valid
-		daysToAdd := int(math.Ceil(startDate.Sub(retVal).Hours() / 24))
-		retVal = retVal.AddDate(0, 0, daysToAdd)
-	}
-	if retVal.After(endDate) {
-		// If the generated time is after the end date, subtract days to make it valid
-		daysToSubtract := int(math.Ceil(retVal.Sub(endDate).Hours() / 24))
-		retVal = retVal.AddDate(0, 0, -daysToSubtract)
-	}
-	return retVal
-}
-
-func getWeekdayInRange(startDate, endDate time.Time) time.Time {
-	if endDate.Before(startDate) {
-		panic("endDate must be after startDate")
-	}
-
-	// Calculate the duration between the two times
-	duration := endDate.Sub(startDate)
-
-	for {
-		// Generate a random duration within the range
-		randomOffset := time.Duration(rand.Int63n(int64(duration)))
-		candidate := startDate.Add(randomOffset)
-
-		// Check if it's a weekday (Monday to Friday)
-		if candidate.Weekday() >= time.Monday && candidate.Weekday() <= time.Friday {
-			return candidate
+	// Configured windows and days override those of the named spikes
+	if spike, ok := workload.(*SpikeWorkload); ok {
+		spike.Location = jobInfo.Location
+		if len(jobInfo.SpikeWindows) > 0 {
+			spike.Windows = jobInfo.SpikeWindows
+		}
+		if jobInfo.SpikeDays != "" {
+			spike.Days = jobInfo.SpikeDays
 		}
 	}
-}
-
-func getWeekendInRange(startDate, endDate time.Time) time.Time {
-	if endDate.Before(startDate) {
-		panic("endDate must be after startDate")
-	}
+	return workload, nil
 
-	// Calculate the duration between the two times
-	duration := endDate.Sub(startDate)
-
-	for {
-		// Generate a random duration within the range
-		randomOffset := time.Duration(rand.Int63n(int64(duration)))
-		candidate := startDate.Add(randomOffset)
+}
 
-		// Check if it's a weekend (Saturday or Sunday)
-		if candidate.Weekday() == time.Saturday || candidate.Weekday() == time.Sunday {
-			return candidate
-		}
+// Note records a policy decision detail on the job for the per-job trace.
+func (j *Job) Note(key, value string) {
+	if j.Decision == nil {
+		j.Decision = make(map[string]string)
 	}
+	j.Decision[key] = value
 }
@@ -2,11 +2,12 @@ package workload
 
 import (
 	"simulator/pkg/directory"
+	"simulator/pkg/environment"
 	"time"
 )
 
 type PolicyInterface interface {
-	GenerateWorkload(jobInfo JobMetadata) ([]*Job, error)
+	GenerateWorkload(env *environment.Environment, jobInfo JobMetadata) ([]*Job, error)
 }
 
 // Defines the various workloads that can be used in the simulation.
@@ -22,13 +23,64 @@ type Job struct {
 	StartTime time.Time                    // When the job is queued
 	DueTime   time.Time                    // When the job is due before SLO violation
 	EndTime   time.Time                    // How long the job will take to run
+
+	QueueDelay time.Duration // Time spent waiting for cluster capacity
+
+	ArrivalTime    time.Time         // When the job entered the system
+	ScheduledStart time.Time         // Start time chosen by the policy
+	Carbon         float64           // Carbon released by the job in gCO2
+	Decision       map[string]string // Policy notes on how the job was scheduled
+
+	SLOClass         string  // SLO class the deadline was drawn from, if any
+	RequiredAccuracy float64 // Minimum accuracy of the model serving the job, 0 for none
+	Tenant           string  // Tenant that submitted the job, if known
+	Component        string  // Mixture component that generated the job, if any
+	Region           string  // Region the job was routed to, empty for the home region
+
+	// Suspend and resume. A job whose current segment ends with work
+	// remaining is suspended and resumes in the next planned window.
+	Plan               []Segment     // Windows later segments may run in, earliest first
+	Remaining          time.Duration // Work left once the current segment ends
+	CheckpointOverhead time.Duration // Time every resumed segment spends restoring its checkpoint
+	Segments           []Segment     // Segments run so far
+}
+
+// Segment is one uninterrupted stretch of a job's execution. Jobs that are
+// never suspended run as a single segment.
+type Segment struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Carbon float64   `json:"carbon_g"` // in gCO2, set once the segment ends
 }
 
 type JobMetadata struct {
-	// The time the job is due before SLO violation
-	DueTime        time.Duration
+	// The time the job is due before SLO violation, the longest deadline
+	// when SLOs is set
+	DueTime time.Duration
+	// Per-job deadline distribution, nil gives every job DueTime
+	SLOs           *SLOMix
 	NumJobs        int
 	WorkloadPolicy string
+	// Share of jobs spike workloads place inside their spike period
+	SpikeFraction float64
+	// Time zone hour windows, days and rate profiles are interpreted in, the
+	// loader's zone when nil
+	Location *time.Location
+	// Hour windows and days of the spike, overriding those of named spikes
+	SpikeWindows []HourWindow
+	SpikeDays    string
+	// Arrivals per hour of the Poisson workloads, with the hourly or weekly
+	// multipliers of the non-homogeneous one
+	ArrivalRate float64
+	RateProfile []float64
+	// States of the Markov-modulated workload
+	MMPPStates []MMPPState
+	// Weighted generators combined by the mixture workload
+	Mixture []MixtureComponent
+	// Arrival log replayed by the trace workload, with its format and alignment
+	TracePath   string
+	TraceFormat string
+	TraceAlign  string
 }
 
 type JobOrigin int
@@ -0,0 +1,57 @@
+package workload
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// WriteJobsCSV writes the arrival and deadline of each job, one job per row,
+// in the CSV layout TraceWorkload replays. The SLO class, tenant and required
+// accuracy get a column each when any job has them set.
+func WriteJobsCSV(w io.Writer, jobs []*Job) error {
+	hasClass := slices.ContainsFunc(jobs, func(job *Job) bool { return job.SLOClass != "" })
+	hasTenant := slices.ContainsFunc(jobs, func(job *Job) bool { return job.Tenant != "" })
+	hasAccuracy := slices.ContainsFunc(jobs, func(job *Job) bool { return job.RequiredAccuracy != 0 })
+	header := []string{"timestamp", "deadline"}
+	if hasClass {
+		header = append(header, "class")
+	}
+	if hasTenant {
+		header = append(header, "tenant")
+	}
+	if hasAccuracy {
+		header = append(header, "required_accuracy")
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing workload header: %w", err)
+	}
+	for _, job := range jobs {
+		record := []string{
+			job.StartTime.Format(time.RFC3339Nano),
+			job.DueTime.Format(time.RFC3339Nano),
+		}
+		if hasClass {
+			record = append(record, job.SLOClass)
+		}
+		if hasTenant {
+			record = append(record, job.Tenant)
+		}
+		if hasAccuracy {
+			accuracy := ""
+			if job.RequiredAccuracy != 0 {
+				accuracy = strconv.FormatFloat(job.RequiredAccuracy, 'g', -1, 64)
+			}
+			record = append(record, accuracy)
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("error writing workload record: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,128 @@
+package workload
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"simulator/pkg/environment"
+	"simulator/pkg/synthetic"
+	"testing"
+	"time"
+)
+
+// testEnvironment returns an environment over a week of synthetic data whose
+// random draws are seeded with seed.
+func testEnvironment(t *testing.T, seed int64) *environment.Environment {
+	t.Helper()
+	model := synthetic.Default()
+	model.Duration = 7 * 24 * time.Hour
+	dataLoader, err := synthetic.NewLoader(model, 1)
+	if err != nil {
+		t.Fatalf("generating trace: %v", err)
+	}
+	return &environment.Environment{
+		Loader: dataLoader,
+		Logger: log.New(io.Discard, "", 0),
+		Rand:   rand.New(rand.NewSource(seed)),
+		Seed:   seed,
+	}
+}
+
+func TestWriteJobsCSVHeader(t *testing.T) {
+	start := time.Date(2023, time.January, 2, 0, 0, 0, 0, time.UTC)
+	plain := &Job{StartTime: start, DueTime: start.Add(time.Hour)}
+	tests := []struct {
+		name string
+		jobs []*Job
+		want []string
+	}{
+		{name: "plain", jobs: []*Job{plain}, want: []string{"timestamp", "deadline"}},
+		{name: "class", jobs: []*Job{plain, {StartTime: start, DueTime: start, SLOClass: "batch"}}, want: []string{"timestamp", "deadline", "class"}},
+		{name: "tenant", jobs: []*Job{{StartTime: start, DueTime: start, Tenant: "a"}, plain}, want: []string{"timestamp", "deadline", "tenant"}},
+		{name: "required accuracy", jobs: []*Job{plain, {StartTime: start, DueTime: start, RequiredAccuracy: 0.8}}, want: []string{"timestamp", "deadline", "required_accuracy"}},
+		{name: "all", jobs: []*Job{{StartTime: start, DueTime: start, SLOClass: "batch", Tenant: "a", RequiredAccuracy: 0.8}}, want: []string{"timestamp", "deadline", "class", "tenant", "required_accuracy"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var written bytes.Buffer
+			if err := WriteJobsCSV(&written, test.jobs); err != nil {
+				t.Fatalf("WriteJobsCSV: %v", err)
+			}
+			records, err := csv.NewReader(&written).ReadAll()
+			if err != nil {
+				t.Fatalf("reading written CSV: %v", err)
+			}
+			if len(records) != len(test.jobs)+1 {
+				t.Fatalf("wrote %d records, want a header and %d rows", len(records), len(test.jobs))
+			}
+			if header := records[0]; len(header) != len(test.want) {
+				t.Fatalf("header is %v, want %v", header, test.want)
+			}
+			for i, name := range test.want {
+				if records[0][i] != name {
+					t.Errorf("header is %v, want %v", records[0], test.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestWriteJobsCSVRoundTrip(t *testing.T) {
+	env := testEnvironment(t, 3)
+	slos, err := ParseSLOMix("interactive:0.3:30m-1h,batch:0.7:6hr")
+	if err != nil {
+		t.Fatalf("ParseSLOMix: %v", err)
+	}
+	jobInfo := NewJobInfo(0, 200, "poisson")
+	jobInfo.SLOs = slos
+	jobInfo.ArrivalRate = 4
+	generated, err := GetWorkload(env, jobInfo)
+	if err != nil {
+		t.Fatalf("GetWorkload: %v", err)
+	}
+	// Only some jobs carry a tenant or an accuracy, the rest leave them empty
+	for i, job := range generated.Jobs {
+		if i%3 == 0 {
+			job.Tenant = "tenant-a"
+		}
+		if i%4 == 0 {
+			job.RequiredAccuracy = 0.75
+		}
+	}
+	path := filepath.Join(t.TempDir(), "workload.csv")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("creating workload file: %v", err)
+	}
+	if err := WriteJobsCSV(file, generated.Jobs); err != nil {
+		t.Fatalf("WriteJobsCSV: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("closing workload file: %v", err)
+	}
+
+	replayInfo := NewJobInfo(0, 0, "trace")
+	replayInfo.SLOs = slos
+	replayInfo.TracePath = path
+	replayed, err := GetWorkload(testEnvironment(t, 4), replayInfo)
+	if err != nil {
+		t.Fatalf("replaying the written workload: %v", err)
+	}
+	if len(replayed.Jobs) != len(generated.Jobs) {
+		t.Fatalf("replayed %d jobs, want %d", len(replayed.Jobs), len(generated.Jobs))
+	}
+	for i, want := range generated.Jobs {
+		got := replayed.Jobs[i]
+		if !got.StartTime.Equal(want.StartTime) || !got.DueTime.Equal(want.DueTime) {
+			t.Errorf("job %d arrives %v due %v, want %v due %v", i, got.StartTime, got.DueTime, want.StartTime, want.DueTime)
+		}
+		if got.SLOClass != want.SLOClass || got.Tenant != want.Tenant || got.RequiredAccuracy != want.RequiredAccuracy {
+			t.Errorf("job %d has class %q, tenant %q and accuracy %f, want %q, %q and %f", i, got.SLOClass, got.Tenant, got.RequiredAccuracy, want.SLOClass, want.Tenant, want.RequiredAccuracy)
+		}
+	}
+}